@@ -0,0 +1,445 @@
+package agentorchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/audit"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/auth"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/httpx"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/id"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+const (
+	agentStatusDraft      = "draft"
+	agentStatusActive     = "active"
+	agentStatusDeprecated = "deprecated"
+	agentStatusRetired    = "retired"
+)
+
+// agentTransitions lists the statuses an agent may move to from each status. Retired is
+// final; deprecated agents still accept runs and may be reactivated.
+var agentTransitions = map[string][]string{
+	agentStatusDraft:      {agentStatusActive, agentStatusRetired},
+	agentStatusActive:     {agentStatusDeprecated, agentStatusRetired},
+	agentStatusDeprecated: {agentStatusActive, agentStatusRetired},
+	agentStatusRetired:    {},
+}
+
+var (
+	agentIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+	errAgentRetired         = errors.New("agent is retired")
+	errAgentVersionNotFound = errors.New("agent version not found")
+)
+
+const maxAgentNameLen = 200
+
+func validAgentStatus(status string) bool {
+	_, ok := agentTransitions[status]
+	return ok
+}
+
+func canTransitionAgent(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range agentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAgentMetadata checks the caller-supplied agent fields and returns a client-facing
+// message describing the first problem found.
+func validateAgentMetadata(name, status string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "name is required", false
+	}
+	if len(name) > maxAgentNameLen {
+		return "name must be at most 200 characters", false
+	}
+	if status != "" && !validAgentStatus(status) {
+		return "status must be one of draft, active, deprecated, retired", false
+	}
+	return "", true
+}
+
+// validateAgentDefinition checks tool bindings and default run options.
+func validateAgentDefinition(def types.AgentDefinition) (string, bool) {
+	seen := make(map[string]bool, len(def.Tools))
+	for _, tool := range def.Tools {
+		if strings.TrimSpace(tool.Name) == "" || strings.TrimSpace(tool.Kind) == "" {
+			return "definition.tools entries require name and kind", false
+		}
+		if seen[tool.Name] {
+			return "definition.tools contains duplicate tool " + tool.Name, false
+		}
+		seen[tool.Name] = true
+	}
+	return validateRunOptions(def.RunOptions, "definition.run_options")
+}
+
+// validateRunOptions checks option ranges; zero values mean "unset".
+func validateRunOptions(opts types.RunOptions, field string) (string, bool) {
+	switch opts.Priority {
+	case "", "low", "normal", "high":
+	default:
+		return field + ".priority must be one of low, normal, high", false
+	}
+	if opts.TimeoutMs < 0 || opts.MaxSteps < 0 {
+		return field + ".timeout_ms and max_steps must not be negative", false
+	}
+	if opts.Retry != nil {
+		if opts.Retry.MaxAttempts < 0 || opts.Retry.MaxAttempts > maxRetryAttempts {
+			return field + ".retry.max_attempts must be between 0 and " + strconv.Itoa(maxRetryAttempts), false
+		}
+		if opts.Retry.BackoffMs < 0 {
+			return field + ".retry.backoff_ms must not be negative", false
+		}
+	}
+	return "", true
+}
+
+// runConfig is the effective configuration of a run after merging the agent definition
+// with the create request.
+type runConfig struct {
+	ModelID      string
+	Instructions string
+	Tools        []types.ToolDescriptor
+	Options      types.RunOptions
+}
+
+// resolveRunConfig merges a run create request over the agent's definition. Request run
+// options override the agent defaults field by field. When the agent binds tools, the
+// request may only narrow them down by name and the agent's descriptors are used; agents
+// without tool bindings accept request tools as descriptors for the model, but never
+// executable configuration: http tools and tool config come from the agent only.
+func resolveRunConfig(def types.AgentDefinition, req types.RunCreateRequest) (runConfig, string, bool) {
+	if msg, ok := validateRunOptions(req.RunOptions, "run_options"); !ok {
+		return runConfig{}, msg, false
+	}
+	cfg := runConfig{
+		ModelID:      def.ModelID,
+		Instructions: def.Instructions,
+		Tools:        def.Tools,
+		Options:      def.RunOptions,
+	}
+	if req.RunOptions.Priority != "" {
+		cfg.Options.Priority = req.RunOptions.Priority
+	}
+	if req.RunOptions.TimeoutMs > 0 {
+		cfg.Options.TimeoutMs = req.RunOptions.TimeoutMs
+	}
+	if req.RunOptions.MaxSteps > 0 {
+		cfg.Options.MaxSteps = req.RunOptions.MaxSteps
+	}
+	cfg.Options.StreamEvents = cfg.Options.StreamEvents || req.RunOptions.StreamEvents
+	if req.RunOptions.Retry != nil {
+		cfg.Options.Retry = req.RunOptions.Retry
+	}
+
+	if len(req.Tooling.Tools) == 0 {
+		return cfg, "", true
+	}
+	if len(def.Tools) == 0 {
+		for _, tool := range req.Tooling.Tools {
+			if tool.Kind == "http" || len(tool.Config) > 0 {
+				return runConfig{}, "tool " + tool.Name + " must be bound to the agent to set kind http or config", false
+			}
+		}
+		cfg.Tools = req.Tooling.Tools
+		return cfg, "", true
+	}
+	bound := make(map[string]types.ToolDescriptor, len(def.Tools))
+	for _, tool := range def.Tools {
+		bound[tool.Name] = tool
+	}
+	cfg.Tools = make([]types.ToolDescriptor, 0, len(req.Tooling.Tools))
+	for _, tool := range req.Tooling.Tools {
+		desc, ok := bound[tool.Name]
+		if !ok {
+			return runConfig{}, "tool " + tool.Name + " is not bound to this agent", false
+		}
+		cfg.Tools = append(cfg.Tools, desc)
+	}
+	return cfg, "", true
+}
+
+// handleAgentCreate serves POST /v1/agents.
+func (s *Server) handleAgentCreate(w http.ResponseWriter, r *http.Request, tenantID string, ac auth.AuthContext) {
+	var req types.AgentCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
+		return
+	}
+	if msg, ok := validateAgentMetadata(req.Name, req.Status); !ok {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", msg, httpx.CorrelationID(r), false)
+		return
+	}
+	if msg, ok := validateAgentDefinition(req.Definition); !ok {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", msg, httpx.CorrelationID(r), false)
+		return
+	}
+	if req.AgentID == "" {
+		req.AgentID = id.New("agt")
+	} else if !agentIDPattern.MatchString(req.AgentID) {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", "agent_id must be 1-64 letters, digits, '_' or '-'", httpx.CorrelationID(r), false)
+		return
+	}
+	if req.Status == "" {
+		req.Status = agentStatusDraft
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	agent := types.Agent{
+		AgentID:     req.AgentID,
+		TenantID:    tenantID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Version:     "1",
+		Status:      req.Status,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Definition:  req.Definition,
+	}
+	if err := s.agents.Create(r.Context(), agent); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAgentExists):
+			httpx.Error(w, http.StatusConflict, "conflict", "agent already exists", httpx.CorrelationID(r), false)
+		case errors.Is(err, storage.ErrInvalidAgent):
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", "invalid agent", httpx.CorrelationID(r), false)
+		default:
+			httpx.Error(w, http.StatusInternalServerError, "agent_persist_failed", "failed to persist agent", httpx.CorrelationID(r), true)
+		}
+		return
+	}
+	if err := s.agents.CreateVersion(r.Context(), agentVersionOf(agent, "")); err != nil && !errors.Is(err, storage.ErrAgentVersionExists) {
+		httpx.Error(w, http.StatusInternalServerError, "agent_persist_failed", "failed to persist agent version", httpx.CorrelationID(r), true)
+		return
+	}
+
+	s.audit.Log(audit.Entry{
+		TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "agents.create", Resource: "agent/" + agent.AgentID, Outcome: "allowed",
+		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+		Meta: map[string]any{"status": agent.Status},
+	})
+	httpx.JSON(w, http.StatusCreated, types.AgentGetResponse{Agent: agent, CorrelationID: httpx.CorrelationID(r)})
+}
+
+// handleAgentUpdate serves PUT /v1/agents/{agent_id}.
+func (s *Server) handleAgentUpdate(w http.ResponseWriter, r *http.Request, tenantID, agentID string, ac auth.AuthContext) {
+	var req types.AgentUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
+		return
+	}
+	if msg, ok := validateAgentMetadata(req.Name, req.Status); !ok {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", msg, httpx.CorrelationID(r), false)
+		return
+	}
+	if req.Definition != nil {
+		if msg, ok := validateAgentDefinition(*req.Definition); !ok {
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", msg, httpx.CorrelationID(r), false)
+			return
+		}
+	}
+
+	s.updateAgent(w, r, tenantID, agentID, ac, "agents.update", true, "", func(agent *types.Agent) error {
+		agent.Name = strings.TrimSpace(req.Name)
+		agent.Description = req.Description
+		if req.Status != "" {
+			agent.Status = req.Status
+		}
+		if req.Definition != nil {
+			agent.Definition = *req.Definition
+		}
+		return nil
+	})
+}
+
+// handleAgentRetire serves DELETE /v1/agents/{agent_id}. Agents are retired rather than
+// removed so that existing runs keep resolving their agent.
+func (s *Server) handleAgentRetire(w http.ResponseWriter, r *http.Request, tenantID, agentID string, ac auth.AuthContext) {
+	s.updateAgent(w, r, tenantID, agentID, ac, "agents.retire", false, "", func(agent *types.Agent) error {
+		agent.Status = agentStatusRetired
+		return nil
+	})
+}
+
+// handleAgentRollback serves POST /v1/agents/{agent_id}/versions/{version}:rollback. The
+// target version's content is recorded as a new version rather than rewriting history.
+func (s *Server) handleAgentRollback(w http.ResponseWriter, r *http.Request, tenantID, agentID, version string, ac auth.AuthContext) {
+	s.updateAgent(w, r, tenantID, agentID, ac, "agents.rollback", true, version, func(agent *types.Agent) error {
+		target, ok, err := s.agents.GetVersion(r.Context(), tenantID, agentID, version)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errAgentVersionNotFound
+		}
+		agent.Name = target.Name
+		agent.Description = target.Description
+		agent.Definition = target.Definition
+		return nil
+	})
+}
+
+// updateAgent applies fn to the stored agent, enforcing status transitions, and writes
+// the response. When versioned is set the result is also recorded as the agent's next
+// immutable version; sourceVersion optionally names the version it was copied from.
+func (s *Server) updateAgent(w http.ResponseWriter, r *http.Request, tenantID, agentID string, ac auth.AuthContext, action string, versioned bool, sourceVersion string, fn func(agent *types.Agent) error) {
+	s.agentMu.Lock()
+	defer s.agentMu.Unlock()
+
+	agent, ok, err := s.agents.Get(r.Context(), tenantID, agentID)
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to load agent", httpx.CorrelationID(r), true)
+		return
+	}
+	if !ok {
+		httpx.Error(w, http.StatusNotFound, "not_found", "agent not found", httpx.CorrelationID(r), false)
+		return
+	}
+
+	before := agent
+	from := agent.Status
+	if err := fn(&agent); err != nil {
+		if errors.Is(err, errAgentVersionNotFound) {
+			httpx.Error(w, http.StatusNotFound, "not_found", "agent version not found", httpx.CorrelationID(r), false)
+			return
+		}
+		httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to load agent version", httpx.CorrelationID(r), true)
+		return
+	}
+	if !canTransitionAgent(from, agent.Status) {
+		httpx.Error(w, http.StatusConflict, "invalid_state_transition", "cannot move agent from "+from+" to "+agent.Status, httpx.CorrelationID(r), false)
+		return
+	}
+	agent.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if versioned {
+		if err := s.recordNextVersion(r, &agent, before, sourceVersion); err != nil {
+			httpx.Error(w, http.StatusInternalServerError, "agent_persist_failed", "failed to persist agent version", httpx.CorrelationID(r), true)
+			return
+		}
+	}
+	if err := s.agents.Save(r.Context(), agent); err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "agent_persist_failed", "failed to persist agent", httpx.CorrelationID(r), true)
+		return
+	}
+
+	meta := map[string]any{"from_status": from, "status": agent.Status}
+	if versioned {
+		meta["from_version"] = before.Version
+		meta["version"] = agent.Version
+	}
+	s.audit.Log(audit.Entry{
+		TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: action, Resource: "agent/" + agentID, Outcome: "allowed",
+		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+		Meta: meta,
+	})
+	httpx.JSON(w, http.StatusOK, types.AgentGetResponse{Agent: agent, CorrelationID: httpx.CorrelationID(r)})
+}
+
+// recordNextVersion assigns agent the next version number and stores the snapshot.
+// Callers hold agentMu. Agents registered before versioning existed have no version
+// records yet; their pre-update state (before) is kept under its old version first.
+func (s *Server) recordNextVersion(r *http.Request, agent *types.Agent, before types.Agent, sourceVersion string) error {
+	versions, err := s.agents.ListVersions(r.Context(), agent.TenantID, agent.AgentID)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 && before.Version != "" {
+		legacy := agentVersionOf(before, "")
+		if err := s.agents.CreateVersion(r.Context(), legacy); err != nil && !errors.Is(err, storage.ErrAgentVersionExists) {
+			return err
+		}
+		versions = append(versions, legacy)
+	}
+	agent.Version = nextAgentVersion(versions)
+	return s.agents.CreateVersion(r.Context(), agentVersionOf(*agent, sourceVersion))
+}
+
+// nextAgentVersion returns one past the highest numeric version.
+func nextAgentVersion(versions []types.AgentVersion) string {
+	max := 0
+	for _, v := range versions {
+		if n, err := strconv.Atoi(v.Version); err == nil && n > max {
+			max = n
+		}
+	}
+	return strconv.Itoa(max + 1)
+}
+
+func agentVersionOf(agent types.Agent, sourceVersion string) types.AgentVersion {
+	return types.AgentVersion{
+		TenantID:      agent.TenantID,
+		AgentID:       agent.AgentID,
+		Version:       agent.Version,
+		Name:          agent.Name,
+		Description:   agent.Description,
+		Definition:    agent.Definition,
+		SourceVersion: sourceVersion,
+		CreatedAt:     agent.UpdatedAt,
+	}
+}
+
+// handleAgentVersionList serves GET /v1/agents/{agent_id}/versions.
+func (s *Server) handleAgentVersionList(w http.ResponseWriter, r *http.Request, tenantID, agentID string) {
+	if _, ok, err := s.agents.Get(r.Context(), tenantID, agentID); err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to load agent", httpx.CorrelationID(r), true)
+		return
+	} else if !ok {
+		httpx.Error(w, http.StatusNotFound, "not_found", "agent not found", httpx.CorrelationID(r), false)
+		return
+	}
+	versions, err := s.agents.ListVersions(r.Context(), tenantID, agentID)
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to list agent versions", httpx.CorrelationID(r), true)
+		return
+	}
+	if versions == nil {
+		versions = []types.AgentVersion{}
+	}
+	httpx.JSON(w, http.StatusOK, types.AgentVersionListResponse{Versions: versions, CorrelationID: httpx.CorrelationID(r)})
+}
+
+// handleAgentVersionGet serves GET /v1/agents/{agent_id}/versions/{version}.
+func (s *Server) handleAgentVersionGet(w http.ResponseWriter, r *http.Request, tenantID, agentID, version string) {
+	v, ok, err := s.agents.GetVersion(r.Context(), tenantID, agentID, version)
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to load agent version", httpx.CorrelationID(r), true)
+		return
+	}
+	if !ok {
+		httpx.Error(w, http.StatusNotFound, "not_found", "agent version not found", httpx.CorrelationID(r), false)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, types.AgentVersionGetResponse{AgentVersion: v, CorrelationID: httpx.CorrelationID(r)})
+}
+
+// runnableAgent loads the agent a run is created for. It returns storage.ErrAgentNotFound
+// for unknown agents and errAgentRetired for retired ones.
+func (s *Server) runnableAgent(r *http.Request, tenantID, agentID string) (types.Agent, error) {
+	agent, ok, err := s.agents.Get(r.Context(), tenantID, agentID)
+	if err != nil {
+		return types.Agent{}, err
+	}
+	if !ok {
+		return types.Agent{}, storage.ErrAgentNotFound
+	}
+	if agent.Status == agentStatusRetired {
+		return agent, errAgentRetired
+	}
+	return agent, nil
+}
@@ -0,0 +1,240 @@
+package agentorchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func decodeAgent(t *testing.T, body []byte) types.Agent {
+	t.Helper()
+	var resp types.AgentGetResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("unmarshal agent response: %v", err)
+	}
+	return resp.Agent
+}
+
+func TestAgentWriteAPILifecycle(t *testing.T) {
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_agents", `{"tenant_id":"tnt_agents"}`)
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_agents_other", `{"tenant_id":"tnt_agents_other"}`)
+
+	for _, body := range []string{`{"description":"no name"}`, `{"name":"x","status":"paused"}`, `{"name":"x","agent_id":"bad id"}`} {
+		if rec := doRequest(t, srv, http.MethodPost, "/v1/agents", "tnt_agents", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents", "tnt_agents", `{"name":"Support Bot"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	generated := decodeAgent(t, rec.Body.Bytes())
+	if !strings.HasPrefix(generated.AgentID, "agt_") || generated.Status != "draft" || generated.TenantID != "tnt_agents" {
+		t.Fatalf("expected generated draft agent, got %+v", generated)
+	}
+
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/agents", "tnt_agents", `{"agent_id":"agt_support","name":"Support","status":"active"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/agents", "tnt_agents", `{"agent_id":"agt_support","name":"Support"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate agent, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, srv, http.MethodPut, "/v1/agents/agt_support", "tnt_agents", `{"name":"Support v2","status":"deprecated"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if updated := decodeAgent(t, rec.Body.Bytes()); updated.Name != "Support v2" || updated.Version != "2" || updated.Status != "deprecated" {
+		t.Fatalf("unexpected updated agent %+v", updated)
+	}
+	if rec := doRequest(t, srv, http.MethodPut, "/v1/agents/agt_support", "tnt_agents_other", `{"name":"hijack"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for other tenant update, got %d", rec.Code)
+	}
+
+	// deprecated agents still accept runs
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_support/runs", "tnt_agents", `{"input":{"type":"text","text":"hi"}}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for deprecated agent run, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_unknown/runs", "tnt_agents", `{"input":{"type":"text","text":"hi"}}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown agent run, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, srv, http.MethodDelete, "/v1/agents/agt_support", "tnt_agents", "")
+	if rec.Code != http.StatusOK || decodeAgent(t, rec.Body.Bytes()).Status != "retired" {
+		t.Fatalf("expected retired agent, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_support/runs", "tnt_agents", `{"input":{"type":"text","text":"hi"}}`)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "agent_retired") {
+		t.Fatalf("expected 409 agent_retired, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, srv, http.MethodPut, "/v1/agents/agt_support", "tnt_agents", `{"name":"Support","status":"active"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 reactivating retired agent, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, srv, http.MethodGet, "/v1/agents", "tnt_agents", "")
+	var list types.AgentListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Agents) != 2 {
+		t.Fatalf("expected 2 agents listed, got %s", rec.Body.String())
+	}
+}
+
+func TestRunInheritsAgentDefinition(t *testing.T) {
+	got := make(chan types.ModelInvokeRequest, 1)
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ModelInvokeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		got <- req
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_def", `{"tenant_id":"tnt_def"}`)
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents", "tnt_def", `{
+		"agent_id": "agt_def", "name": "Defined", "status": "active",
+		"definition": {
+			"model_id": "model-a",
+			"instructions": "Be brief.",
+			"tools": [
+				{"name": "lookup", "kind": "builtin"},
+				{"name": "search", "kind": "http", "config": {"url": "http://search.local"}}
+			],
+			"run_options": {"priority": "low", "timeout_ms": 30000, "max_steps": 5}
+		}
+	}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_def/runs", "tnt_def",
+		`{"input":{"type":"text","text":"hi"},"tooling":{"tools":[{"name":"search"}]},"run_options":{"max_steps":3}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp types.RunCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal create response: %v", err)
+	}
+	run := resp.Run
+	if run.ModelID != "model-a" || run.Instructions != "Be brief." {
+		t.Fatalf("expected model and instructions from agent, got %+v", run)
+	}
+	if run.RunOptions.Priority != "low" || run.RunOptions.TimeoutMs != 30000 || run.RunOptions.MaxSteps != 3 {
+		t.Fatalf("expected merged run options, got %+v", run.RunOptions)
+	}
+	if run.Tooling == nil || len(run.Tooling.Tools) != 1 || run.Tooling.Tools[0].Kind != "http" {
+		t.Fatalf("expected narrowed tool with agent descriptor, got %+v", run.Tooling)
+	}
+
+	select {
+	case req := <-got:
+		if req.ModelID != "model-a" || req.Input["instructions"] != "Be brief." {
+			t.Fatalf("expected agent model and instructions sent to model-policy, got %+v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("model-policy was not called")
+	}
+
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_def/runs", "tnt_def",
+		`{"input":{"type":"text","text":"hi"},"tooling":{"tools":[{"name":"shell","kind":"builtin"}]}}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unbound tool, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestResolveRunConfigRejectsRequestSuppliedToolConfig(t *testing.T) {
+	for _, tool := range []types.ToolDescriptor{
+		{Name: "fetch", Kind: "http"},
+		{Name: "fetch", Kind: "builtin", Config: map[string]any{"url": "http://169.254.169.254/"}},
+	} {
+		req := types.RunCreateRequest{Tooling: types.Tooling{Tools: []types.ToolDescriptor{tool}}}
+		if _, msg, ok := resolveRunConfig(types.AgentDefinition{}, req); ok {
+			t.Fatalf("expected %+v to be rejected for an agent without tool bindings", tool)
+		} else if msg == "" {
+			t.Fatalf("expected a message for %+v", tool)
+		}
+	}
+	req := types.RunCreateRequest{Tooling: types.Tooling{Tools: []types.ToolDescriptor{{Name: "lookup", Kind: "builtin"}}}}
+	if cfg, _, ok := resolveRunConfig(types.AgentDefinition{}, req); !ok || len(cfg.Tools) != 1 {
+		t.Fatalf("expected a plain tool descriptor to be accepted, got %+v %v", cfg, ok)
+	}
+}
+
+func TestAgentVersionsPinAndRollback(t *testing.T) {
+	models := make(chan string, 4)
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ModelInvokeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		models <- req.ModelID
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_ver", `{"tenant_id":"tnt_ver"}`)
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents", "tnt_ver", `{"agent_id":"agt_ver","name":"V","status":"active","definition":{"model_id":"model-a"}}`)
+	if rec.Code != http.StatusCreated || decodeAgent(t, rec.Body.Bytes()).Version != "1" {
+		t.Fatalf("expected version 1 on create, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, srv, http.MethodPut, "/v1/agents/agt_ver", "tnt_ver", `{"name":"V","definition":{"model_id":"model-b"}}`)
+	if rec.Code != http.StatusOK || decodeAgent(t, rec.Body.Bytes()).Version != "2" {
+		t.Fatalf("expected version 2 on update, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, srv, http.MethodGet, "/v1/agents/agt_ver/versions", "tnt_ver", "")
+	var list types.AgentVersionListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Versions) != 2 {
+		t.Fatalf("expected 2 versions, got %s", rec.Body.String())
+	}
+	if list.Versions[0].Definition.ModelID != "model-a" || list.Versions[1].Definition.ModelID != "model-b" {
+		t.Fatalf("expected versions to keep their definitions, got %+v", list.Versions)
+	}
+
+	runAndModel := func(body string) (types.Run, string) {
+		t.Helper()
+		rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_ver/runs", "tnt_ver", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp types.RunCreateResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		select {
+		case m := <-models:
+			return resp.Run, m
+		case <-time.After(5 * time.Second):
+			t.Fatalf("model-policy was not called")
+			return types.Run{}, ""
+		}
+	}
+	if run, model := runAndModel(`{"input":{"type":"text","text":"hi"}}`); run.AgentVersion != "2" || model != "model-b" {
+		t.Fatalf("expected current version 2 with model-b, got %s %s", run.AgentVersion, model)
+	}
+	if run, model := runAndModel(`{"agent_version":"1","input":{"type":"text","text":"hi"}}`); run.AgentVersion != "1" || model != "model-a" {
+		t.Fatalf("expected pinned version 1 with model-a, got %s %s", run.AgentVersion, model)
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_ver/runs", "tnt_ver", `{"agent_version":"9","input":{"type":"text","text":"hi"}}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown pinned version, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_ver/versions/1:rollback", "tnt_ver", "")
+	rolled := decodeAgent(t, rec.Body.Bytes())
+	if rec.Code != http.StatusOK || rolled.Version != "3" || rolled.Definition.ModelID != "model-a" {
+		t.Fatalf("expected rollback to create version 3 with model-a, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, srv, http.MethodGet, "/v1/agents/agt_ver/versions/3", "tnt_ver", "")
+	var got types.AgentVersionGetResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.AgentVersion.SourceVersion != "1" {
+		t.Fatalf("expected version 3 sourced from 1, got %s", rec.Body.String())
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_ver/versions/7:rollback", "tnt_ver", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 rolling back to unknown version, got %d", rec.Code)
+	}
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_ver_other", `{"tenant_id":"tnt_ver_other"}`)
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/agents/agt_ver/versions", "tnt_ver_other", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected versions to be tenant scoped, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package agentorchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/audit"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/auth"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/httpx"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+var errApprovalMismatch = errors.New("decision does not match the pending approval")
+
+// handleRunDecision serves POST /v1/runs/{run_id}:approve and :reject for runs that are
+// waiting_for_input. Approving re-queues the run to resume from its checkpoint with the
+// pending tool call allowed; rejecting fails the run with approval_rejected.
+func (s *Server) handleRunDecision(w http.ResponseWriter, r *http.Request, tenantID, runID string, ac auth.AuthContext, approve bool) {
+	if r.Method != http.MethodPost {
+		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+		return
+	}
+	var req types.RunDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
+		return
+	}
+	decision, action := "rejected", "runs.reject"
+	if approve {
+		decision, action = "approved", "runs.approve"
+	}
+
+	var pending types.RunApproval
+	run, err := s.exec.updateRun(r.Context(), tenantID, runID, func(run *types.Run) error {
+		if run.Status != "waiting_for_input" || run.PendingApproval == nil {
+			return errInvalidStateTransition
+		}
+		pending = *run.PendingApproval
+		if req.ToolCallID != "" && req.ToolCallID != pending.ToolCallID {
+			return errApprovalMismatch
+		}
+		run.PendingApproval = nil
+		if approve {
+			run.Checkpoint = cloneCheckpoint(run.Checkpoint)
+			run.Checkpoint.Approved = append(run.Checkpoint.Approved, pending.ToolCallID)
+			run.Status = "queued"
+			return nil
+		}
+		run.Checkpoint = nil
+		run.Status = "failed"
+		run.CompletedAt = time.Now().UTC().Format(time.RFC3339)
+		run.Error = &types.RunError{
+			Code:    "approval_rejected",
+			Message: "tool call " + pending.ToolName + " was rejected",
+			Details: map[string]any{"tool_call_id": pending.ToolCallID, "tool_name": pending.ToolName, "reason": req.Reason},
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errRunNotFound):
+			httpx.Error(w, http.StatusNotFound, "not_found", "run not found", httpx.CorrelationID(r), false)
+		case errors.Is(err, errInvalidStateTransition):
+			httpx.Error(w, http.StatusConflict, "invalid_state_transition", "run in "+run.Status+" state is not waiting for input", httpx.CorrelationID(r), false)
+		case errors.Is(err, errApprovalMismatch):
+			httpx.Error(w, http.StatusConflict, "approval_mismatch", "tool_call_id does not match the pending approval", httpx.CorrelationID(r), false)
+		default:
+			httpx.Error(w, http.StatusInternalServerError, "run_persist_failed", "failed to persist run decision", httpx.CorrelationID(r), true)
+		}
+		return
+	}
+
+	s.audit.Log(audit.Entry{
+		TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: action, Resource: "run/" + runID, Outcome: "allowed",
+		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+		Meta: map[string]any{"tool_call_id": pending.ToolCallID, "tool_name": pending.ToolName, "reason": req.Reason},
+	})
+	s.exec.emit(r.Context(), run, pending.StepID, "agentos.run.approval.resolved", map[string]any{
+		"decision": decision, "tool_call_id": pending.ToolCallID, "tool_name": pending.ToolName,
+		"principal_id": ac.PrincipalID, "reason": req.Reason,
+	})
+
+	if approve {
+		_ = s.exec.Enqueue(tenantID, runID, run.RunOptions.Priority)
+	} else {
+		s.limiter.ReleaseRunSlot(tenantID, runID)
+		s.exec.emit(r.Context(), run, "", "agentos.run.failed", map[string]any{"status": run.Status, "error": run.Error})
+	}
+
+	httpx.JSON(w, http.StatusOK, types.RunDecisionResponse{Run: run, CorrelationID: httpx.CorrelationID(r)})
+}
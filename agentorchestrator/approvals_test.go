@@ -0,0 +1,145 @@
+package agentorchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// newApprovalServer returns a server whose agent agt_gated binds a "transfer" tool that
+// requires approval; the fake model requests one transfer and then answers.
+func newApprovalServer(t *testing.T, tenantID string, toolCalls *atomic.Int32) *Server {
+	t.Helper()
+	toolSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		toolCalls.Add(1)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(toolSrv.Close)
+
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ModelInvokeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := req.Input["tool_results"]; ok {
+			_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "transferred"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{
+			"tool_calls": []any{map[string]any{"id": "call_1", "name": "transfer", "arguments": map[string]any{"amount": 10}}},
+		}})
+	})
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", tenantID, `{"tenant_id":"`+tenantID+`"}`)
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents", tenantID, `{
+		"agent_id": "agt_gated", "name": "Gated", "status": "active",
+		"definition": {"tools": [{"name": "transfer", "kind": "http", "requires_approval": true, "config": {"url": "`+toolSrv.URL+`"}}]}
+	}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	return srv
+}
+
+func startGatedRun(t *testing.T, srv *Server, tenantID string) types.Run {
+	t.Helper()
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_gated/runs", tenantID, `{"input":{"type":"text","text":"pay"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp types.RunCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal create response: %v", err)
+	}
+	run := waitForStatus(t, srv, tenantID, resp.Run.RunID, "waiting_for_input", "completed", "failed")
+	if run.Status != "waiting_for_input" || run.PendingApproval == nil || run.PendingApproval.ToolCallID != "call_1" {
+		t.Fatalf("expected run waiting for approval of call_1, got %s %+v", run.Status, run.PendingApproval)
+	}
+	return run
+}
+
+func TestApproveResumesPausedRun(t *testing.T) {
+	var toolCalls atomic.Int32
+	srv := newApprovalServer(t, "tnt_hitl", &toolCalls)
+	run := startGatedRun(t, srv, "tnt_hitl")
+	if toolCalls.Load() != 0 {
+		t.Fatalf("expected tool not to run before approval")
+	}
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":approve", "tnt_hitl", `{"tool_call_id":"call_other"}`)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "approval_mismatch") {
+		t.Fatalf("expected 409 approval_mismatch, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":approve", "tnt_hitl_other", "")
+	if rec.Code == http.StatusOK {
+		t.Fatalf("expected approval to be tenant scoped")
+	}
+	rec = doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":approve", "tnt_hitl", `{"tool_call_id":"call_1","reason":"looks fine"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	done := waitForStatus(t, srv, "tnt_hitl", run.RunID, "completed", "failed")
+	if done.Status != "completed" || done.Output == nil || done.Output.Text != "transferred" {
+		t.Fatalf("expected completed run, got %s %+v (error=%+v)", done.Status, done.Output, done.Error)
+	}
+	if done.PendingApproval != nil || done.Checkpoint != nil {
+		t.Fatalf("expected approval state cleared, got %+v %+v", done.PendingApproval, done.Checkpoint)
+	}
+	if toolCalls.Load() != 1 {
+		t.Fatalf("expected exactly one tool call after approval, got %d", toolCalls.Load())
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":approve", "tnt_hitl", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 approving a finished run, got %d", rec.Code)
+	}
+
+	var eventTypes []string
+	for _, ev := range readEvents(t, srv, "tnt_hitl", "/v1/runs/"+run.RunID+"/events") {
+		eventTypes = append(eventTypes, ev.Type)
+	}
+	joined := strings.Join(eventTypes, ",")
+	for _, want := range []string{"agentos.run.approval.requested", "agentos.run.approval.resolved", "agentos.run.resumed", "agentos.tool.call.completed"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected %s in event log, got %v", want, eventTypes)
+		}
+	}
+
+	auditLog, err := os.ReadFile(strings.TrimPrefix(os.Getenv("AGENTOS_AUDIT_SINK"), "file:"))
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	if !strings.Contains(string(auditLog), `"action":"runs.approve"`) || !strings.Contains(string(auditLog), "looks fine") {
+		t.Fatalf("expected approval decision in audit log, got %s", auditLog)
+	}
+}
+
+func TestRejectFailsPausedRun(t *testing.T) {
+	t.Setenv("AGENTOS_QUOTA_CONCURRENT_RUNS", "1")
+	var toolCalls atomic.Int32
+	srv := newApprovalServer(t, "tnt_hitl_rej", &toolCalls)
+	run := startGatedRun(t, srv, "tnt_hitl_rej")
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":reject", "tnt_hitl_rej", `{"reason":"not authorized"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp types.RunDecisionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal decision response: %v", err)
+	}
+	if resp.Run.Status != "failed" || resp.Run.Error == nil || resp.Run.Error.Code != "approval_rejected" {
+		t.Fatalf("expected failed with approval_rejected, got %s %+v", resp.Run.Status, resp.Run.Error)
+	}
+	if toolCalls.Load() != 0 {
+		t.Fatalf("expected rejected tool not to run")
+	}
+
+	// the rejected run's concurrency slot is released
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_gated/runs", "tnt_hitl_rej", `{"input":{"type":"text","text":"again"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected slot released after rejection, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,110 @@
+package agentorchestrator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errEgressDenied signals an outbound call to a destination the egress policy refuses.
+var errEgressDenied = errors.New("egress denied")
+
+// egressPolicy guards outbound HTTP calls made on behalf of tenants (http tools,
+// webhooks), whose destinations are tenant-controlled. Addresses are checked after DNS
+// resolution, at dial time, so a public name cannot resolve to an internal address.
+type egressPolicy struct {
+	// allowedHosts are the hosts calls may go to; empty allows any host. An entry with a
+	// leading dot (.example.com) also matches its subdomains.
+	allowedHosts []string
+	// allowPrivate permits loopback and private addresses, for local development only.
+	// Link-local addresses (e.g. cloud metadata endpoints) are refused regardless.
+	allowPrivate bool
+}
+
+// egressPolicyFromEnv reads the allowed hosts from the comma-separated hostsVar and
+// AGENTOS_EGRESS_ALLOW_PRIVATE=1.
+func egressPolicyFromEnv(hostsVar string) egressPolicy {
+	p := egressPolicy{allowPrivate: strings.TrimSpace(os.Getenv("AGENTOS_EGRESS_ALLOW_PRIVATE")) == "1"}
+	if hostsVar != "" {
+		for _, h := range strings.Split(os.Getenv(hostsVar), ",") {
+			if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+				p.allowedHosts = append(p.allowedHosts, h)
+			}
+		}
+	}
+	return p
+}
+
+// checkURL verifies the scheme and host of u before a call is made.
+func (p egressPolicy) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not allowed", errEgressDenied, u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: url has no host", errEgressDenied)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if err := p.checkIP(ip); err != nil {
+			return err
+		}
+	}
+	if len(p.allowedHosts) == 0 {
+		return nil
+	}
+	for _, allowed := range p.allowedHosts {
+		if host == allowed || strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %s is not in the allowed hosts", errEgressDenied, host)
+}
+
+// checkIP refuses addresses internal to the deployment.
+func (p egressPolicy) checkIP(ip net.IP) error {
+	switch {
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast(), ip.IsUnspecified(), ip.IsMulticast():
+	case !p.allowPrivate && (ip.IsLoopback() || ip.IsPrivate()):
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: address %s is internal", errEgressDenied, ip)
+}
+
+// client returns an HTTP client enforcing p on every connection and redirect. It never
+// uses a proxy, which would hide the destination address from the check.
+func (p egressPolicy) client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("%w: unresolved address %s", errEgressDenied, address)
+			}
+			return p.checkIP(ip)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return p.checkURL(req.URL)
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package agentorchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// emit appends an event to the run's log and hands it to webhook delivery. Event
+// persistence is best-effort: a failed append must not fail the run itself.
+func (e *executor) emit(ctx context.Context, run types.Run, stepID, eventType string, payload map[string]any) {
+	if e.events == nil {
+		return
+	}
+	event, err := e.events.Append(ctx, types.Event{
+		Type:     eventType,
+		TenantID: run.TenantID,
+		AgentID:  run.AgentID,
+		RunID:    run.RunID,
+		StepID:   stepID,
+		Trace:    traceContext(run.RunID),
+		Payload:  payload,
+	})
+	if err == nil {
+		e.hub.notify(runRef{TenantID: run.TenantID, RunID: run.RunID})
+		e.webhooks.Dispatch(event)
+	}
+}
+
+// isTerminalEventType reports whether an event closes a run's event stream.
+func isTerminalEventType(eventType string) bool {
+	switch eventType {
+	case "agentos.run.completed", "agentos.run.failed", "agentos.run.canceled", "agentos.run.timed_out":
+		return true
+	}
+	return false
+}
+
+// eventHub wakes up SSE streams tailing a run when new events are appended to its log.
+// Notifications are process-local; streams also re-read the log on every keepalive tick.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[runRef]map[chan struct{}]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[runRef]map[chan struct{}]struct{})}
+}
+
+// subscribe returns a channel that receives a value after each append to the run's log
+// (appends while a value is pending coalesce into it) and a function that unsubscribes
+// it, which the stream must call when it ends.
+func (h *eventHub) subscribe(ref runRef) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs, ok := h.subscribers[ref]
+	if !ok {
+		subs = make(map[chan struct{}]struct{})
+		h.subscribers[ref] = subs
+	}
+	subs[ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(subs, ch)
+		if len(subs) == 0 {
+			delete(h.subscribers, ref)
+		}
+	}
+}
+
+func (h *eventHub) notify(ref runRef) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[ref] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// traceContext derives a stable W3C trace id from the run id so that all events of a run
+// share one trace, with a fresh span id per call.
+func traceContext(runID string) types.TraceContext {
+	sum := sha256.Sum256([]byte(runID))
+	traceID := hex.EncodeToString(sum[:16])
+	span := make([]byte, 8)
+	_, _ = rand.Read(span)
+	spanID := hex.EncodeToString(span)
+	return types.TraceContext{
+		Traceparent: "00-" + traceID + "-" + spanID + "-01",
+		SpanID:      spanID,
+	}
+}
@@ -0,0 +1,572 @@
+package agentorchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/id"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/quota"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+var (
+	errRunNotFound            = errors.New("run not found")
+	errInvalidStateTransition = errors.New("invalid state transition")
+)
+
+type runRef struct {
+	TenantID string
+	RunID    string
+}
+
+// executor drives queued runs to a terminal state in the background, independently of
+// client polling. Runs are handed to a fixed worker pool through a bounded scheduler that
+// orders them by priority with fair sharing between tenants.
+type executor struct {
+	runs     storage.RunStore
+	events   storage.EventStore
+	sessions storage.SessionStore
+	hub      *eventHub
+	limiter  quota.Limiter
+	models   *modelClient
+	modelID  string
+	tools    *toolRuntime
+	webhooks *webhookDispatcher
+
+	workers int
+	sched   *runScheduler
+	// overflowed is set when a run could not be scheduled; the sweep then re-enqueues
+	// the runs left queued in the RunStore every sweepInterval.
+	overflowed    atomic.Bool
+	sweepInterval time.Duration
+
+	// mu serializes read-modify-write updates of run state so that worker transitions
+	// and API actions (e.g. cancel) never overwrite each other.
+	mu       sync.Mutex
+	inflight map[runRef]context.CancelFunc
+	// backoff holds retries waiting out their backoff, which the sweep leaves alone.
+	backoff map[runRef]struct{}
+
+	// sessionMu serializes session read-modify-write updates (turn appends and deletes).
+	sessionMu        sync.Mutex
+	sessionMaxTokens int
+}
+
+func newExecutorFromEnv(runs storage.RunStore, events storage.EventStore, sessions storage.SessionStore, webhooks storage.WebhookStore, limiter quota.Limiter) *executor {
+	modelID := strings.TrimSpace(os.Getenv("AGENTOS_DEFAULT_MODEL_ID"))
+	if modelID == "" {
+		modelID = "local-stub-llm"
+	}
+	return &executor{
+		runs:     runs,
+		events:   events,
+		sessions: sessions,
+		hub:      newEventHub(),
+		limiter:  limiter,
+		models:   newModelClientFromEnv(),
+		modelID:  modelID,
+		tools:    newToolRuntimeFromEnv(),
+		webhooks: newWebhookDispatcherFromEnv(webhooks, events),
+		workers:  envInt("AGENTOS_EXECUTOR_WORKERS", 4),
+		sched:    newRunScheduler(envInt("AGENTOS_EXECUTOR_QUEUE_SIZE", 1024)),
+		inflight: make(map[runRef]context.CancelFunc),
+		backoff:  make(map[runRef]struct{}),
+
+		sweepInterval: time.Duration(envInt("AGENTOS_EXECUTOR_SWEEP_MS", 5000)) * time.Millisecond,
+
+		sessionMaxTokens: envInt("AGENTOS_SESSION_MAX_TOKENS", 4000),
+	}
+}
+
+// recoverRuns rebuilds executor state from the RunStore after a restart. Every unfinished
+// run gets its concurrency slot back and slots still held by finished or missing runs are
+// released; runs interrupted while running are queued again and all queued runs are handed
+// to the scheduler. Runs waiting for input stay paused.
+func (e *executor) recoverRuns(ctx context.Context) error {
+	runs, err := e.runs.ListByStatus(ctx, "queued", "running", "waiting_for_input")
+	if err != nil {
+		return err
+	}
+	unfinished := make(map[runRef]struct{}, len(runs))
+	for _, run := range runs {
+		unfinished[runRef{TenantID: run.TenantID, RunID: run.RunID}] = struct{}{}
+		e.limiter.RestoreRunSlot(run.TenantID, run.RunID)
+	}
+	e.releaseStaleSlots(ctx, unfinished)
+	for _, run := range runs {
+		switch run.Status {
+		case "waiting_for_input":
+			continue
+		case "running":
+			requeued, err := e.updateRun(ctx, run.TenantID, run.RunID, func(run *types.Run) error {
+				if run.Status != "running" {
+					return errInvalidStateTransition
+				}
+				run.Status = "queued"
+				return nil
+			})
+			if err != nil {
+				continue
+			}
+			e.emit(ctx, requeued, "", "agentos.run.requeued", map[string]any{"status": requeued.Status, "reason": "restart"})
+		}
+		_ = e.Enqueue(run.TenantID, run.RunID, run.RunOptions.Priority)
+	}
+	return nil
+}
+
+// releaseStaleSlots frees the concurrency slots of runs that are not among the unfinished
+// runs recovered, such as runs whose terminal release was lost in a crash or a Redis outage.
+// A run missing from the listing is looked up again before its slot is released, since
+// another replica sharing the limiter may have started it since.
+func (e *executor) releaseStaleSlots(ctx context.Context, unfinished map[runRef]struct{}) {
+	for tenantID, runIDs := range e.limiter.RunSlots() {
+		for _, runID := range runIDs {
+			if _, ok := unfinished[runRef{TenantID: tenantID, RunID: runID}]; ok {
+				continue
+			}
+			run, ok, err := e.runs.Get(ctx, tenantID, runID)
+			if err != nil || (ok && !isTerminalStatus(run.Status)) {
+				continue
+			}
+			e.limiter.ReleaseRunSlot(tenantID, runID)
+		}
+	}
+}
+
+// Start launches the worker pool, the overflow sweep and webhook delivery; they stop when
+// ctx is canceled.
+func (e *executor) Start(ctx context.Context) {
+	e.webhooks.Start(ctx)
+	for i := 0; i < e.workers; i++ {
+		go e.worker(ctx)
+	}
+	go e.sweep(ctx)
+}
+
+// Enqueue schedules a queued run for execution at the given run_options priority. It
+// never blocks; when the scheduler is full the run stays queued in the RunStore, where
+// the sweep picks it up once workers catch up, and false is returned. The run is never
+// dropped, so callers that only need it to run eventually may ignore the result.
+func (e *executor) Enqueue(tenantID, runID, priority string) bool {
+	if !e.sched.Push(runRef{TenantID: tenantID, RunID: runID}, priority) {
+		e.overflowed.Store(true)
+		return false
+	}
+	return true
+}
+
+// sweep re-enqueues the runs left queued in the RunStore after the scheduler overflowed,
+// oldest first, until they are all scheduled.
+func (e *executor) sweep(ctx context.Context) {
+	ticker := time.NewTicker(e.sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !e.overflowed.Swap(false) {
+			continue
+		}
+		runs, err := e.runs.ListByStatus(ctx, "queued")
+		if err != nil {
+			e.overflowed.Store(true)
+			continue
+		}
+		for _, run := range runs {
+			e.mu.Lock()
+			_, waiting := e.backoff[runRef{TenantID: run.TenantID, RunID: run.RunID}]
+			e.mu.Unlock()
+			if !waiting && !e.Enqueue(run.TenantID, run.RunID, run.RunOptions.Priority) {
+				break
+			}
+		}
+	}
+}
+
+// Abort cancels the in-flight execution of a run, if any.
+func (e *executor) Abort(tenantID, runID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if cancel, ok := e.inflight[runRef{TenantID: tenantID, RunID: runID}]; ok {
+		cancel()
+	}
+}
+
+func (e *executor) worker(ctx context.Context) {
+	for {
+		ref, ok := e.sched.Pop(ctx)
+		if !ok {
+			return
+		}
+		e.execute(ctx, ref)
+	}
+}
+
+func (e *executor) execute(parent context.Context, ref runRef) {
+	run, err := e.updateRun(parent, ref.TenantID, ref.RunID, func(run *types.Run) error {
+		if run.Status != "queued" {
+			// canceled (or already picked up) before a worker got to it
+			return errInvalidStateTransition
+		}
+		run.Status = "running"
+		if run.StartedAt == "" {
+			run.StartedAt = time.Now().UTC().Format(time.RFC3339)
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	cp := cloneCheckpoint(run.Checkpoint)
+	if cp != nil {
+		e.emit(parent, run, "", "agentos.run.resumed", map[string]any{"status": run.Status})
+	} else {
+		cp = &types.RunCheckpoint{}
+		e.emit(parent, run, "", "agentos.run.started", map[string]any{"status": run.Status})
+	}
+
+	// the timeout counts execution time only: not time spent queued or waiting for input
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if run.RunOptions.TimeoutMs > 0 {
+		remaining := time.Duration(run.RunOptions.TimeoutMs)*time.Millisecond - time.Duration(cp.ElapsedMs)*time.Millisecond
+		ctx, cancel = context.WithTimeout(parent, remaining)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	e.mu.Lock()
+	e.inflight[ref] = cancel
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.inflight, ref)
+		e.mu.Unlock()
+		cancel()
+	}()
+
+	started := time.Now()
+	output, approval, runErr := e.runSteps(ctx, run, cp)
+	if parent.Err() != nil {
+		// shutting down: leave the run as-is rather than failing it
+		return
+	}
+	cp.ElapsedMs += time.Since(started).Milliseconds()
+
+	if approval != nil && runErr == nil {
+		run, err = e.updateRun(context.Background(), ref.TenantID, ref.RunID, func(run *types.Run) error {
+			if isTerminalStatus(run.Status) {
+				return errInvalidStateTransition
+			}
+			// the run keeps its concurrency slot while it waits for a decision
+			run.Status = "waiting_for_input"
+			run.PendingApproval = approval
+			run.Checkpoint = cp
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		e.emit(context.Background(), run, approval.StepID, "agentos.run.approval.requested", map[string]any{
+			"status": run.Status, "tool_call_id": approval.ToolCallID, "tool_name": approval.ToolName, "arguments": approval.Arguments,
+		})
+		return
+	}
+
+	timedOut := runErr != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		runErr = &types.RunError{
+			Code:    "run_timeout",
+			Message: "run exceeded its timeout",
+			Details: map[string]any{"timeout_ms": run.RunOptions.TimeoutMs},
+		}
+	}
+
+	run, err = e.updateRun(context.Background(), ref.TenantID, ref.RunID, func(run *types.Run) error {
+		if isTerminalStatus(run.Status) {
+			// canceled while executing; the cancel path already released the slot
+			return errInvalidStateTransition
+		}
+		run.CompletedAt = time.Now().UTC().Format(time.RFC3339)
+		run.Checkpoint = nil
+		if runErr != nil {
+			run.Status = "failed"
+			if timedOut {
+				run.Status = "timed_out"
+			}
+			run.Error = runErr
+			if shouldAutoRetry(*run) {
+				// reserved in the same transition so a concurrent :retry cannot also retry it
+				run.RetriedBy = id.New("run")
+			}
+			return nil
+		}
+		run.Status = "completed"
+		run.Output = output
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if run.Status == "completed" && run.SessionID != "" {
+		// before the completed event, so a client that has seen it finds the turn in the
+		// session
+		e.recordSessionTurns(context.Background(), run)
+	}
+	if run.RetriedBy != "" {
+		e.scheduleRetry(run)
+	}
+	e.limiter.ReleaseRunSlot(ref.TenantID, ref.RunID)
+	switch run.Status {
+	case "timed_out":
+		e.emit(context.Background(), run, "", "agentos.run.timed_out", map[string]any{"status": run.Status, "error": run.Error})
+		return
+	case "failed":
+		e.emit(context.Background(), run, "", "agentos.run.failed", map[string]any{"status": run.Status, "error": run.Error})
+		return
+	}
+	e.emit(context.Background(), run, "", "agentos.run.completed", map[string]any{"status": run.Status, "output": run.Output})
+}
+
+// defaultMaxSteps bounds runs that do not set run_options.max_steps, so a model that
+// keeps requesting tools cannot loop forever.
+const defaultMaxSteps = 32
+
+// runSteps executes the run's steps from checkpoint cp and returns its final output or
+// error. Each model step may request tool calls; their results are fed back to the next
+// model step until the model answers without requesting tools. Model and tool steps both
+// count against the run's step budget. Before a call to a tool that requires approval,
+// runSteps stops and returns the approval to request; cp then holds the state to resume
+// from once the call is approved.
+func (e *executor) runSteps(ctx context.Context, run types.Run, cp *types.RunCheckpoint) (*types.RunOutput, *types.RunApproval, *types.RunError) {
+	input := map[string]any{}
+	if run.Input != nil {
+		input["type"] = run.Input.Type
+		input["text"] = run.Input.Text
+	}
+	if run.Instructions != "" {
+		input["instructions"] = run.Instructions
+	}
+	tools := map[string]types.ToolDescriptor{}
+	if run.Tooling != nil && len(run.Tooling.Tools) > 0 {
+		input["tools"] = run.Tooling.Tools
+		for _, tool := range run.Tooling.Tools {
+			tools[tool.Name] = tool
+		}
+	}
+	if run.SessionID != "" {
+		history, err := e.sessionHistory(ctx, run)
+		if err != nil {
+			return nil, nil, &types.RunError{Code: "session_unavailable", Message: "failed to load session history", Details: map[string]any{"session_id": run.SessionID}}
+		}
+		if len(history) > 0 {
+			input["history"] = history
+		}
+	}
+	modelID := run.ModelID
+	if modelID == "" {
+		modelID = e.modelID
+	}
+
+	maxSteps := run.RunOptions.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+	exceeded := &types.RunError{Code: "max_steps_exceeded", Message: "run exceeded its step budget", Details: map[string]any{"max_steps": maxSteps}}
+
+	for {
+		if len(cp.PendingCalls) == 0 {
+			if cp.Steps >= maxSteps {
+				return nil, nil, exceeded
+			}
+			if len(cp.ToolResults) > 0 {
+				input["tool_results"] = cp.ToolResults
+			}
+			cp.Steps++
+			output, runErr := e.modelStep(ctx, run, modelID, input)
+			if runErr != nil {
+				return nil, nil, runErr
+			}
+			calls := toolCallsFromModel(output)
+			if len(calls) == 0 {
+				return outputFromModel(output), nil, nil
+			}
+			for i := range calls {
+				if calls[i].ID == "" {
+					calls[i].ID = id.New("tcall")
+				}
+			}
+			cp.PendingCalls = calls
+		}
+
+		for len(cp.PendingCalls) > 0 {
+			call := cp.PendingCalls[0]
+			if tools[call.Name].RequiresApproval && !containsString(cp.Approved, call.ID) {
+				return nil, &types.RunApproval{
+					StepID:      id.New("stp"),
+					ToolCallID:  call.ID,
+					ToolName:    call.Name,
+					Arguments:   call.Arguments,
+					RequestedAt: time.Now().UTC().Format(time.RFC3339),
+				}, nil
+			}
+			if cp.Steps >= maxSteps {
+				return nil, nil, exceeded
+			}
+			cp.Steps++
+			cp.ToolResults = append(cp.ToolResults, e.toolStep(ctx, run, tools, call))
+			cp.PendingCalls = cp.PendingCalls[1:]
+		}
+	}
+}
+
+// modelStep invokes the model once and returns its output.
+func (e *executor) modelStep(ctx context.Context, run types.Run, modelID string, input map[string]any) (map[string]any, *types.RunError) {
+	stepID := id.New("stp")
+	e.emit(ctx, run, stepID, "agentos.run.step.started", map[string]any{"step_kind": "model", "name": "invoke"})
+	e.emit(ctx, run, stepID, "agentos.model.requested", map[string]any{"model_ref": modelID, "operation": "chat"})
+
+	started := time.Now()
+	resp, runErr := e.models.Invoke(ctx, run.TenantID, types.ModelInvokeRequest{
+		Operation: "chat",
+		ModelID:   modelID,
+		Input:     input,
+		Trace:     map[string]any{"run_id": run.RunID, "agent_id": run.AgentID, "traceparent": traceContext(run.RunID).Traceparent},
+	})
+	latency := time.Since(started).Milliseconds()
+	if runErr != nil {
+		e.emit(ctx, run, stepID, "agentos.model.completed", map[string]any{
+			"model_ref": modelID, "operation": "chat", "status": "error", "error": runErr, "latency_ms": latency,
+		})
+		e.emit(ctx, run, stepID, "agentos.run.step.completed", map[string]any{"step_kind": "model", "status": "error"})
+		return nil, runErr
+	}
+	e.emit(ctx, run, stepID, "agentos.model.completed", map[string]any{
+		"model_ref": modelID, "operation": "chat", "status": "ok", "usage": resp.Usage, "latency_ms": latency,
+	})
+	e.emit(ctx, run, stepID, "agentos.run.step.completed", map[string]any{"step_kind": "model", "status": "ok"})
+	return resp.Output, nil
+}
+
+// toolStep executes one tool call and returns the result handed back to the model. Tool
+// failures do not fail the run; the model sees the error and decides how to proceed.
+func (e *executor) toolStep(ctx context.Context, run types.Run, tools map[string]types.ToolDescriptor, call types.ToolCall) map[string]any {
+	stepID := id.New("stp")
+	e.emit(ctx, run, stepID, "agentos.run.step.started", map[string]any{"step_kind": "tool", "name": call.Name})
+
+	tool, ok := tools[call.Name]
+	e.emit(ctx, run, stepID, "agentos.tool.call.started", map[string]any{
+		"tool_call_id": call.ID, "tool_name": call.Name, "kind": tool.Kind, "input": call.Arguments,
+		"timeout_ms": e.tools.timeoutFor(tool).Milliseconds(),
+	})
+
+	started := time.Now()
+	var output map[string]any
+	var runErr *types.RunError
+	if ok {
+		output, runErr = e.tools.Invoke(ctx, run, tool, call.Arguments)
+	} else {
+		runErr = &types.RunError{Code: "tool_not_found", Message: "tool " + call.Name + " is not bound to this run"}
+	}
+	duration := time.Since(started).Milliseconds()
+
+	result := map[string]any{"tool_call_id": call.ID, "name": call.Name, "arguments": call.Arguments}
+	completed := map[string]any{"tool_call_id": call.ID, "tool_name": call.Name, "duration_ms": duration}
+	status := "ok"
+	if runErr != nil {
+		status = "error"
+		result["error"] = runErr
+		completed["error"] = runErr
+	} else {
+		result["output"] = output
+		completed["output"] = output
+	}
+	completed["status"] = status
+	e.emit(ctx, run, stepID, "agentos.tool.call.completed", completed)
+	e.emit(ctx, run, stepID, "agentos.run.step.completed", map[string]any{"step_kind": "tool", "status": status})
+	return result
+}
+
+// updateRun loads a run, applies fn and persists the result while holding the executor
+// lock. An error returned by fn aborts the update and is passed through unchanged.
+func (e *executor) updateRun(ctx context.Context, tenantID, runID string, fn func(run *types.Run) error) (types.Run, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	run, ok, err := e.runs.Get(ctx, tenantID, runID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	if !ok {
+		return types.Run{}, errRunNotFound
+	}
+	if err := fn(&run); err != nil {
+		return run, err
+	}
+	if err := e.runs.Save(ctx, run); err != nil {
+		return run, err
+	}
+	return run, nil
+}
+
+// cloneCheckpoint copies cp so it can be changed without touching the run the store
+// holds, which readers of the run may be encoding concurrently.
+func cloneCheckpoint(cp *types.RunCheckpoint) *types.RunCheckpoint {
+	if cp == nil {
+		return nil
+	}
+	out := *cp
+	out.ToolResults = append([]map[string]any(nil), cp.ToolResults...)
+	out.PendingCalls = append([]types.ToolCall(nil), cp.PendingCalls...)
+	out.Approved = append([]string(nil), cp.Approved...)
+	return &out
+}
+
+func outputFromModel(output map[string]any) *types.RunOutput {
+	out := &types.RunOutput{Type: "text"}
+	if v, ok := output["type"].(string); ok && v != "" {
+		out.Type = v
+	}
+	if v, ok := output["text"].(string); ok {
+		out.Text = v
+	}
+	return out
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func isTerminalStatus(status string) bool {
+	switch status {
+	case "completed", "failed", "canceled", "timed_out":
+		return true
+	}
+	return false
+}
+
+func envInt(key string, def int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i <= 0 {
+		return def
+	}
+	return i
+}
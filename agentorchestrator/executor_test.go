@@ -0,0 +1,509 @@
+package agentorchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// newExecutingServer returns a started server with isolated stores that sends model
+// calls to the given fake model-policy handler.
+func newExecutingServer(t *testing.T, modelPolicy http.HandlerFunc) *Server {
+	t.Helper()
+	mp := httptest.NewServer(modelPolicy)
+	t.Cleanup(mp.Close)
+
+	t.Setenv("AGENTOS_MODEL_POLICY_URL", mp.URL)
+	srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	srv.Start(ctx)
+	return srv
+}
+
+func doRequest(t *testing.T, srv *Server, method, path, tenantID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = bytes.NewBufferString(body)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("X-Tenant-Id", tenantID)
+	req.Header.Set("X-Scopes", "tenants:admin")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func createTestRun(t *testing.T, srv *Server, tenantID, body string) types.Run {
+	t.Helper()
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", tenantID, `{"tenant_id":"`+tenantID+`"}`)
+	seedAgent(t, srv, tenantID, "agt_test")
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_test/runs", tenantID, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for run creation, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp types.RunCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal create response: %v", err)
+	}
+	return resp.Run
+}
+
+func waitForStatus(t *testing.T, srv *Server, tenantID, runID string, statuses ...string) types.Run {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := doRequest(t, srv, http.MethodGet, "/v1/runs/"+runID, tenantID, "")
+		var resp types.RunGetResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal get response: %v", err)
+		}
+		for _, s := range statuses {
+			if resp.Run.Status == s {
+				return resp.Run
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run %s did not reach %v, last status %s", runID, statuses, resp.Run.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExecutorCompletesRunWithModelOutput(t *testing.T) {
+	var got types.ModelInvokeRequest
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models:invoke" {
+			t.Errorf("unexpected model-policy path %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Tenant-Id") != "tnt_exec" {
+			t.Errorf("expected tenant header, got %q", r.Header.Get("X-Tenant-Id"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{
+			Output: map[string]any{"type": "text", "text": "model says hi"},
+			Usage:  map[string]any{"total_tokens": 10},
+		})
+	})
+
+	run := createTestRun(t, srv, "tnt_exec", `{"input":{"type":"text","text":"hello"}}`)
+	done := waitForStatus(t, srv, "tnt_exec", run.RunID, "completed", "failed")
+
+	if done.Status != "completed" {
+		t.Fatalf("expected completed, got %s (error=%+v)", done.Status, done.Error)
+	}
+	if done.Output == nil || done.Output.Text != "model says hi" {
+		t.Fatalf("expected model output on run, got %+v", done.Output)
+	}
+	if done.StartedAt == "" || done.CompletedAt == "" {
+		t.Fatalf("expected started_at and completed_at, got %q %q", done.StartedAt, done.CompletedAt)
+	}
+	if got.Input["text"] != "hello" {
+		t.Fatalf("expected run input forwarded to model, got %+v", got.Input)
+	}
+}
+
+func TestExecutorFailsRunOnModelError(t *testing.T) {
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`{"error":{"code":"provider_error","message":"upstream down","retryable":true}}`))
+	})
+
+	run := createTestRun(t, srv, "tnt_exec_fail", `{"input":{"type":"text","text":"hello"}}`)
+	done := waitForStatus(t, srv, "tnt_exec_fail", run.RunID, "completed", "failed")
+
+	if done.Status != "failed" {
+		t.Fatalf("expected failed, got %s", done.Status)
+	}
+	if done.Error == nil || done.Error.Code != "provider_error" {
+		t.Fatalf("expected provider_error, got %+v", done.Error)
+	}
+}
+
+func TestExecutorTimesOutRunAndReleasesSlot(t *testing.T) {
+	t.Setenv("AGENTOS_QUOTA_CONCURRENT_RUNS", "1")
+	release := make(chan struct{})
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ModelInvokeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Input["text"] == "hang" {
+			<-release
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	t.Cleanup(func() { close(release) })
+
+	run := createTestRun(t, srv, "tnt_timeout", `{"input":{"type":"text","text":"hang"},"run_options":{"timeout_ms":100}}`)
+	done := waitForStatus(t, srv, "tnt_timeout", run.RunID, "completed", "failed", "timed_out")
+	if done.Status != "timed_out" || done.Error == nil || done.Error.Code != "run_timeout" {
+		t.Fatalf("expected timed_out with run_timeout, got %s %+v", done.Status, done.Error)
+	}
+	if done.CompletedAt == "" {
+		t.Fatalf("expected completed_at on timed out run")
+	}
+
+	events := readEvents(t, srv, "tnt_timeout", "/v1/runs/"+run.RunID+"/events")
+	if last := events[len(events)-1]; last.Type != "agentos.run.timed_out" {
+		t.Fatalf("expected agentos.run.timed_out as terminal event, got %s", last.Type)
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":cancel", "tnt_timeout", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 canceling a timed out run, got %d", rec.Code)
+	}
+
+	// the only concurrency slot must have been released by the timeout
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_test/runs", "tnt_timeout", `{"input":{"type":"text","text":"again"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected slot released after timeout, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExecutorEnforcesMaxSteps(t *testing.T) {
+	t.Setenv("AGENTOS_QUOTA_CONCURRENT_RUNS", "1")
+	var mu sync.Mutex
+	calls := 0
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{
+			"tool_calls": []any{map[string]any{"name": "lookup", "arguments": map[string]any{}}},
+		}})
+	})
+
+	run := createTestRun(t, srv, "tnt_steps", `{"input":{"type":"text","text":"hello"},"run_options":{"max_steps":3}}`)
+	done := waitForStatus(t, srv, "tnt_steps", run.RunID, "completed", "failed")
+	if done.Status != "failed" || done.Error == nil || done.Error.Code != "max_steps_exceeded" {
+		t.Fatalf("expected failed with max_steps_exceeded, got %s %+v", done.Status, done.Error)
+	}
+	// model, tool, model; the next tool call would be step four
+	mu.Lock()
+	if calls != 2 {
+		t.Fatalf("expected 2 model calls within a 3 step budget, got %d", calls)
+	}
+	mu.Unlock()
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_test/runs", "tnt_steps", `{"input":{"type":"text","text":"again"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected slot released after step budget failure, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func readEvents(t *testing.T, srv *Server, tenantID, path string) []types.Event {
+	t.Helper()
+	rec := doRequest(t, srv, http.MethodGet, path, tenantID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for events, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var events []types.Event
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var env types.EventEnvelope
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &env); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		events = append(events, env.Event)
+	}
+	return events
+}
+
+func TestExecutorWritesRunEventLog(t *testing.T) {
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+
+	run := createTestRun(t, srv, "tnt_events", `{"input":{"type":"text","text":"hello"}}`)
+	waitForStatus(t, srv, "tnt_events", run.RunID, "completed", "failed")
+
+	events := readEvents(t, srv, "tnt_events", "/v1/runs/"+run.RunID+"/events")
+	wantTypes := []string{
+		"agentos.run.created",
+		"agentos.run.started",
+		"agentos.run.step.started",
+		"agentos.model.requested",
+		"agentos.model.completed",
+		"agentos.run.step.completed",
+		"agentos.run.completed",
+	}
+	if len(events) != len(wantTypes) {
+		t.Fatalf("expected %d events, got %d: %+v", len(wantTypes), len(events), events)
+	}
+	traceID := strings.Split(events[0].Trace.Traceparent, "-")[1]
+	for i, ev := range events {
+		if ev.Type != wantTypes[i] {
+			t.Fatalf("event %d: expected %s, got %s", i, wantTypes[i], ev.Type)
+		}
+		if ev.Sequence != i+1 {
+			t.Fatalf("event %d: expected sequence %d, got %d", i, i+1, ev.Sequence)
+		}
+		if strings.Split(ev.Trace.Traceparent, "-")[1] != traceID {
+			t.Fatalf("expected all events to share trace id, got %s", ev.Trace.Traceparent)
+		}
+	}
+
+	resumed := readEvents(t, srv, "tnt_events", "/v1/runs/"+run.RunID+"/events?from_sequence=5")
+	if len(resumed) != 2 || resumed[0].Sequence != 6 {
+		t.Fatalf("expected replay after sequence 5, got %+v", resumed)
+	}
+
+	if other := doRequest(t, srv, http.MethodGet, "/v1/runs/"+run.RunID+"/events", "tnt_events_other", ""); other.Code == http.StatusOK {
+		t.Fatalf("expected events to be tenant scoped, got %d", other.Code)
+	}
+}
+
+func TestEventStreamTailsRunUntilTerminal(t *testing.T) {
+	t.Setenv("AGENTOS_SSE_KEEPALIVE_MS", "20")
+	release := make(chan struct{})
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	api := httptest.NewServer(srv.Handler())
+	defer api.Close()
+
+	run := createTestRun(t, srv, "tnt_stream", `{"input":{"type":"text","text":"hello"}}`)
+
+	req, _ := http.NewRequest(http.MethodGet, api.URL+"/v1/runs/"+run.RunID+"/events", nil)
+	req.Header.Set("X-Tenant-Id", "tnt_stream")
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string, 256)
+	go func() {
+		defer close(lines)
+		buf := make([]byte, 4096)
+		var pending string
+		for {
+			n, err := resp.Body.Read(buf)
+			pending += string(buf[:n])
+			for {
+				i := strings.Index(pending, "\n")
+				if i < 0 {
+					break
+				}
+				lines <- pending[:i]
+				pending = pending[i+1:]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	next := func() string {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return "<eof>"
+			}
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for stream output")
+			return ""
+		}
+	}
+
+	// Resumed after sequence 1, so the first event is run.started (sequence 2).
+	if line := next(); line != "id: 2" {
+		t.Fatalf("expected id: 2 first, got %q", line)
+	}
+	sawKeepalive := false
+	for !sawKeepalive {
+		if next() == ": keepalive" {
+			sawKeepalive = true
+		}
+	}
+
+	close(release)
+	var ids []string
+	for {
+		line := next()
+		if line == "<eof>" {
+			break
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+	}
+	if len(ids) == 0 || ids[len(ids)-1] != "7" {
+		t.Fatalf("expected stream to end after terminal event 7, got ids %v", ids)
+	}
+}
+
+func TestRestartRebuildsSlotsAndRequeuesRuns(t *testing.T) {
+	t.Setenv("AGENTOS_QUOTA_CONCURRENT_RUNS", "3")
+	first := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	warm := createTestRun(t, first, "tnt_restart", `{"input":{"type":"text","text":"warm up"}}`)
+	waitForStatus(t, first, "tnt_restart", warm.RunID, "completed", "failed")
+
+	// runs left behind by a previous process
+	store, err := storage.NewFileRunStore(os.Getenv("AGENTOS_RUN_STORE_FILE"))
+	if err != nil {
+		t.Fatalf("open run store: %v", err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	input := &types.RunInput{Type: "text", Text: "resume me"}
+	for _, run := range []types.Run{
+		{TenantID: "tnt_restart", AgentID: "agt_test", RunID: "run_queued", Status: "queued", CreatedAt: now, Input: input},
+		{TenantID: "tnt_restart", AgentID: "agt_test", RunID: "run_running", Status: "running", CreatedAt: now, StartedAt: now, Input: input},
+		{TenantID: "tnt_restart", AgentID: "agt_test", RunID: "run_paused", Status: "waiting_for_input", CreatedAt: now, Input: input,
+			PendingApproval: &types.RunApproval{StepID: "stp_1", ToolCallID: "tcall_1", ToolName: "refund"}, Checkpoint: &types.RunCheckpoint{}},
+		{TenantID: "tnt_restart", AgentID: "agt_test", RunID: "run_done", Status: "completed", CreatedAt: now, CompletedAt: now},
+	} {
+		if err := store.Create(context.Background(), run); err != nil {
+			t.Fatalf("seed %s: %v", run.RunID, err)
+		}
+	}
+
+	srv, err := New("test")
+	if err != nil {
+		t.Fatalf("New server error: %v", err)
+	}
+	if got := srv.limiter.ConcurrentRuns("tnt_restart"); got != 3 {
+		t.Fatalf("expected slots rebuilt for the 3 unfinished runs, got %d", got)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	srv.Start(ctx)
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_restart", `{"tenant_id":"tnt_restart"}`)
+
+	for _, runID := range []string{"run_queued", "run_running"} {
+		if done := waitForStatus(t, srv, "tnt_restart", runID, "completed", "failed"); done.Status != "completed" {
+			t.Fatalf("expected %s to complete after restart, got %s %+v", runID, done.Status, done.Error)
+		}
+	}
+	// slots are released just after the terminal status is persisted
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.limiter.ConcurrentRuns("tnt_restart") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected only the paused run to hold a slot, got %d", srv.limiter.ConcurrentRuns("tnt_restart"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// cancel and a second cancel release the paused run's slot once
+	doRequest(t, srv, http.MethodPost, "/v1/runs/run_paused:cancel", "tnt_restart", "")
+	doRequest(t, srv, http.MethodPost, "/v1/runs/run_paused:cancel", "tnt_restart", "")
+	if got := srv.limiter.ConcurrentRuns("tnt_restart"); got != 0 {
+		t.Fatalf("expected no slots held, got %d", got)
+	}
+}
+
+func TestRestartReclaimsStaleRedisSlots(t *testing.T) {
+	mr := miniredis.RunT(t)
+	t.Setenv("AGENTOS_QUOTA_REDIS_URL", "redis://"+mr.Addr())
+	newTestServer(t)
+
+	// runs left behind by a previous process
+	store, err := storage.NewFileRunStore(os.Getenv("AGENTOS_RUN_STORE_FILE"))
+	if err != nil {
+		t.Fatalf("open run store: %v", err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, run := range []types.Run{
+		{TenantID: "tnt_stale", AgentID: "agt_test", RunID: "run_paused", Status: "waiting_for_input", CreatedAt: now,
+			PendingApproval: &types.RunApproval{StepID: "stp_1", ToolCallID: "tcall_1", ToolName: "refund"}, Checkpoint: &types.RunCheckpoint{}},
+		{TenantID: "tnt_stale", AgentID: "agt_test", RunID: "run_done", Status: "completed", CreatedAt: now, CompletedAt: now},
+	} {
+		if err := store.Create(context.Background(), run); err != nil {
+			t.Fatalf("seed %s: %v", run.RunID, err)
+		}
+	}
+	// slots whose terminal release never reached Redis
+	mr.SAdd("agentos:quota:agent-orchestrator:slots:tnt_stale", "run_done", "run_gone")
+
+	srv, err := New("test")
+	if err != nil {
+		t.Fatalf("New server error: %v", err)
+	}
+	members, err := mr.Members("agentos:quota:agent-orchestrator:slots:tnt_stale")
+	if err != nil {
+		t.Fatalf("read slot set: %v", err)
+	}
+	if len(members) != 1 || members[0] != "run_paused" {
+		t.Fatalf("expected only the paused run to keep its slot, got %v", members)
+	}
+	if got := srv.limiter.ConcurrentRuns("tnt_stale"); got != 1 {
+		t.Fatalf("expected one slot held, got %d", got)
+	}
+}
+
+func TestSweepSchedulesRunsAfterQueueOverflow(t *testing.T) {
+	t.Setenv("AGENTOS_EXECUTOR_WORKERS", "1")
+	t.Setenv("AGENTOS_EXECUTOR_QUEUE_SIZE", "1")
+	t.Setenv("AGENTOS_EXECUTOR_SWEEP_MS", "20")
+	release := make(chan struct{})
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ModelInvokeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Input["text"] == "hang" {
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+
+	busy := createTestRun(t, srv, "tnt_overflow", `{"input":{"type":"text","text":"hang"}}`)
+	waitForStatus(t, srv, "tnt_overflow", busy.RunID, "running")
+	queued := createTestRun(t, srv, "tnt_overflow", `{"input":{"type":"text","text":"next"}}`)
+	overflow := createTestRun(t, srv, "tnt_overflow", `{"input":{"type":"text","text":"overflow"}}`)
+	if !srv.exec.overflowed.Load() {
+		t.Fatalf("expected the scheduler to overflow")
+	}
+	close(release)
+
+	for _, run := range []types.Run{busy, queued, overflow} {
+		if done := waitForStatus(t, srv, "tnt_overflow", run.RunID, "completed", "failed"); done.Status != "completed" {
+			t.Fatalf("expected %s to complete, got %s %+v", run.RunID, done.Status, done.Error)
+		}
+	}
+}
+
+func TestEventHubForgetsSubscribersWhenStreamsEnd(t *testing.T) {
+	hub := newEventHub()
+	ref := runRef{TenantID: "tnt_hub", RunID: "run_1"}
+	first, unsubscribeFirst := hub.subscribe(ref)
+	second, unsubscribeSecond := hub.subscribe(ref)
+
+	hub.notify(ref)
+	hub.notify(ref)
+	for _, ch := range []<-chan struct{}{first, second} {
+		select {
+		case <-ch:
+		default:
+			t.Fatalf("expected every subscriber woken")
+		}
+	}
+
+	unsubscribeFirst()
+	if len(hub.subscribers) != 1 {
+		t.Fatalf("expected the run kept while a stream still tails it")
+	}
+	unsubscribeSecond()
+	if len(hub.subscribers) != 0 {
+		t.Fatalf("expected no entries left once every stream ended, got %d", len(hub.subscribers))
+	}
+}
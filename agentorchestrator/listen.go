@@ -1,11 +1,15 @@
 package agentorchestrator
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 func ListenAndServe(addr, version string) error {
 	s, err := New(version)
 	if err != nil {
 		return err
 	}
+	s.Start(context.Background())
 	return http.ListenAndServe(addr, s.Handler())
 }
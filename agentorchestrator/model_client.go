@@ -0,0 +1,90 @@
+package agentorchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// modelClient calls the model-policy service on behalf of executing runs.
+type modelClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func newModelClientFromEnv() *modelClient {
+	base := strings.TrimSpace(os.Getenv("AGENTOS_MODEL_POLICY_URL"))
+	if base == "" {
+		base = "http://localhost:8082"
+	}
+	return &modelClient{
+		BaseURL: strings.TrimRight(base, "/"),
+		Client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Invoke calls POST /v1/models:invoke. Failures are returned as a RunError so the
+// executor can persist them on the run as-is.
+func (c *modelClient) Invoke(ctx context.Context, tenantID string, req types.ModelInvokeRequest) (types.ModelInvokeResponse, *types.RunError) {
+	body, _ := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/v1/models:invoke", bytes.NewReader(body))
+	if err != nil {
+		return types.ModelInvokeResponse{}, &types.RunError{Code: "internal", Message: err.Error()}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Tenant-Id", tenantID)
+
+	resp, err := c.Client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return types.ModelInvokeResponse{}, &types.RunError{Code: "canceled", Message: "model invocation aborted"}
+		}
+		return types.ModelInvokeResponse{}, &types.RunError{
+			Code:    "dependency_unavailable",
+			Message: "model-policy unavailable",
+			Details: map[string]any{"service": "model-policy", "retryable": true},
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var decoded struct {
+			Error struct {
+				Code      string `json:"code"`
+				Message   string `json:"message"`
+				Retryable bool   `json:"retryable"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&decoded)
+		code := decoded.Error.Code
+		if code == "" {
+			code = "model_invoke_failed"
+		}
+		msg := decoded.Error.Message
+		if msg == "" {
+			msg = fmt.Sprintf("model-policy returned %s", resp.Status)
+		}
+		return types.ModelInvokeResponse{}, &types.RunError{
+			Code:    code,
+			Message: msg,
+			Details: map[string]any{"service": "model-policy", "status": resp.StatusCode, "retryable": decoded.Error.Retryable},
+		}
+	}
+
+	var out types.ModelInvokeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return types.ModelInvokeResponse{}, &types.RunError{
+			Code:    "model_invoke_failed",
+			Message: "invalid model-policy response",
+			Details: map[string]any{"service": "model-policy"},
+		}
+	}
+	return out, nil
+}
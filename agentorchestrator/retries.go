@@ -0,0 +1,240 @@
+package agentorchestrator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/audit"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/auth"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/httpx"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/id"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/metrics"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/quota"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+const (
+	// maxRetryAttempts caps run_options.retry.max_attempts.
+	maxRetryAttempts = 10
+	// maxRetryBackoff caps the doubling delay between automatic retries.
+	maxRetryBackoff = 5 * time.Minute
+)
+
+var errAlreadyRetried = errors.New("run already retried")
+
+// retryableRunErrorCodes are retried by a policy without retry_on in addition to errors
+// whose details mark them retryable.
+var retryableRunErrorCodes = []string{"dependency_unavailable", "run_timeout", "session_unavailable"}
+
+// isRetryableStatus reports whether a run in status may be retried.
+func isRetryableStatus(status string) bool {
+	return status == "failed" || status == "timed_out"
+}
+
+// runAttempt returns the run's position in its retry chain; runs created before attempts
+// were recorded count as the first.
+func runAttempt(run types.Run) int {
+	if run.Attempt < 1 {
+		return 1
+	}
+	return run.Attempt
+}
+
+// shouldAutoRetry reports whether the run's retry policy asks for another attempt after
+// the run ended with its current error.
+func shouldAutoRetry(run types.Run) bool {
+	policy := run.RunOptions.Retry
+	if policy == nil || run.Error == nil || runAttempt(run) >= policy.MaxAttempts || run.RetriedBy != "" {
+		return false
+	}
+	if len(policy.RetryOn) > 0 {
+		return containsString(policy.RetryOn, run.Error.Code)
+	}
+	if retryable, _ := run.Error.Details["retryable"].(bool); retryable {
+		return true
+	}
+	return containsString(retryableRunErrorCodes, run.Error.Code)
+}
+
+// retryBackoff returns the delay before the automatic retry of the given attempt.
+func retryBackoff(policy *types.RetryPolicy, attempt int) time.Duration {
+	if policy == nil || policy.BackoffMs <= 0 {
+		return 0
+	}
+	delay := time.Duration(policy.BackoffMs) * time.Millisecond
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// retryRunOf clones parent's request (input, context, tooling and run options) into a
+// new queued run that continues its retry chain. The retry runs against the same agent
+// version, model and instructions as the parent.
+func retryRunOf(parent types.Run, runID string) types.Run {
+	return types.Run{
+		TenantID:     parent.TenantID,
+		AgentID:      parent.AgentID,
+		AgentVersion: parent.AgentVersion,
+		RunID:        runID,
+		Status:       "queued",
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		EventsURL:    "/v1/runs/" + runID + "/events",
+		Input:        parent.Input,
+		ModelID:      parent.ModelID,
+		Instructions: parent.Instructions,
+		Tooling:      parent.Tooling,
+		RunOptions:   parent.RunOptions,
+		Context:      parent.Context,
+		SessionID:    parent.SessionID,
+		Attempt:      runAttempt(parent) + 1,
+		ParentRunID:  parent.RunID,
+	}
+}
+
+// scheduleRetry creates the automatic retry the parent run reserved as RetriedBy and
+// queues it once its backoff has elapsed. The retry takes a concurrency slot regardless
+// of the tenant's limit, since it replaces the parent's slot, which the caller releases.
+func (e *executor) scheduleRetry(parent types.Run) {
+	retry := retryRunOf(parent, parent.RetriedBy)
+	e.limiter.RestoreRunSlot(retry.TenantID, retry.RunID)
+	if err := e.runs.Create(context.Background(), retry); err != nil {
+		e.limiter.ReleaseRunSlot(retry.TenantID, retry.RunID)
+		_, _ = e.updateRun(context.Background(), parent.TenantID, parent.RunID, func(run *types.Run) error {
+			run.RetriedBy = ""
+			return nil
+		})
+		return
+	}
+	delay := retryBackoff(parent.RunOptions.Retry, runAttempt(parent))
+	e.emit(context.Background(), parent, "", "agentos.run.retry.scheduled", map[string]any{
+		"retry_run_id": retry.RunID, "attempt": retry.Attempt, "backoff_ms": delay.Milliseconds(), "error": parent.Error,
+	})
+	e.emit(context.Background(), retry, "", "agentos.run.created", map[string]any{
+		"status": retry.Status, "parent_run_id": parent.RunID, "attempt": retry.Attempt,
+	})
+	if delay <= 0 {
+		_ = e.Enqueue(retry.TenantID, retry.RunID, retry.RunOptions.Priority)
+		return
+	}
+	ref := runRef{TenantID: retry.TenantID, RunID: retry.RunID}
+	e.mu.Lock()
+	e.backoff[ref] = struct{}{}
+	e.mu.Unlock()
+	time.AfterFunc(delay, func() {
+		e.mu.Lock()
+		delete(e.backoff, ref)
+		e.mu.Unlock()
+		_ = e.Enqueue(retry.TenantID, retry.RunID, retry.RunOptions.Priority)
+	})
+}
+
+// handleRetry serves POST /v1/runs/{run_id}:retry. It re-runs a failed or timed out run
+// as a new run linked to it through parent_run_id. Each run can be retried once; retry
+// the newest run of a chain to try again.
+func (s *Server) handleRetry(w http.ResponseWriter, r *http.Request, tenantID, runID string, ac auth.AuthContext) {
+	if r.Method != http.MethodPost {
+		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+		return
+	}
+	qps := s.limiter.AllowQPS(tenantID)
+	quota.SetHeaders(w.Header(), qps)
+	if !qps.Allowed {
+		metrics.IncQuotaDenied("agent-orchestrator", "runs_create_qps")
+		httpx.Error(w, http.StatusTooManyRequests, "quota_exceeded", "run create QPS exceeded", httpx.CorrelationID(r), true)
+		s.audit.Log(audit.Entry{
+			TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "runs.retry", Resource: "run/" + runID, Outcome: "denied",
+			CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+			Meta: map[string]any{"reason": "qps_exceeded"},
+		})
+		return
+	}
+
+	parent, ok, err := s.runs.Get(r.Context(), tenantID, runID)
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "run_lookup_failed", "failed to load run", httpx.CorrelationID(r), true)
+		return
+	}
+	if !ok {
+		httpx.Error(w, http.StatusNotFound, "not_found", "run not found", httpx.CorrelationID(r), false)
+		return
+	}
+	if _, err := s.runnableAgent(r, tenantID, parent.AgentID); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAgentNotFound):
+			httpx.Error(w, http.StatusNotFound, "not_found", "agent not found", httpx.CorrelationID(r), false)
+		case errors.Is(err, errAgentRetired):
+			httpx.Error(w, http.StatusConflict, "agent_retired", "agent is retired", httpx.CorrelationID(r), false)
+		default:
+			httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to load agent", httpx.CorrelationID(r), true)
+		}
+		return
+	}
+	retryID := id.New("run")
+	if !s.limiter.AcquireRunSlot(tenantID, retryID) {
+		metrics.IncQuotaDenied("agent-orchestrator", "runs_concurrency")
+		quota.SetRetryAfter(w.Header(), quota.SlotRetryAfter)
+		httpx.Error(w, http.StatusTooManyRequests, "quota_exceeded", "concurrent runs exceeded", httpx.CorrelationID(r), true)
+		s.audit.Log(audit.Entry{
+			TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "runs.retry", Resource: "run/" + runID, Outcome: "denied",
+			CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+			Meta: map[string]any{"reason": "concurrent_exceeded"},
+		})
+		return
+	}
+
+	parent, err = s.exec.updateRun(r.Context(), tenantID, runID, func(run *types.Run) error {
+		if !isRetryableStatus(run.Status) {
+			return errInvalidStateTransition
+		}
+		if run.RetriedBy != "" {
+			return errAlreadyRetried
+		}
+		run.RetriedBy = retryID
+		return nil
+	})
+	if err != nil {
+		s.limiter.ReleaseRunSlot(tenantID, retryID)
+		switch {
+		case errors.Is(err, errRunNotFound):
+			httpx.Error(w, http.StatusNotFound, "not_found", "run not found", httpx.CorrelationID(r), false)
+		case errors.Is(err, errInvalidStateTransition):
+			httpx.Error(w, http.StatusConflict, "invalid_state_transition", "cannot retry run in "+parent.Status+" state", httpx.CorrelationID(r), false)
+		case errors.Is(err, errAlreadyRetried):
+			httpx.Error(w, http.StatusConflict, "already_retried", "run was already retried by "+parent.RetriedBy, httpx.CorrelationID(r), false)
+		default:
+			httpx.Error(w, http.StatusInternalServerError, "run_persist_failed", "failed to persist run", httpx.CorrelationID(r), true)
+		}
+		return
+	}
+
+	run := retryRunOf(parent, retryID)
+	if err := s.runs.Create(r.Context(), run); err != nil {
+		s.limiter.ReleaseRunSlot(tenantID, retryID)
+		_, _ = s.exec.updateRun(context.Background(), tenantID, runID, func(run *types.Run) error {
+			run.RetriedBy = ""
+			return nil
+		})
+		httpx.Error(w, http.StatusInternalServerError, "run_persist_failed", "failed to persist run", httpx.CorrelationID(r), true)
+		return
+	}
+
+	s.audit.Log(audit.Entry{
+		TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "runs.retry", Resource: "run/" + retryID, Outcome: "allowed",
+		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+		Meta: map[string]any{"agent_id": run.AgentID, "parent_run_id": runID, "attempt": run.Attempt},
+	})
+	s.exec.emit(r.Context(), run, "", "agentos.run.created", map[string]any{
+		"status": run.Status, "parent_run_id": runID, "attempt": run.Attempt,
+	})
+
+	_ = s.exec.Enqueue(tenantID, retryID, run.RunOptions.Priority)
+
+	httpx.JSON(w, http.StatusCreated, types.RunCreateResponse{Run: run, CorrelationID: httpx.CorrelationID(r)})
+}
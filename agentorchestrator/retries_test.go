@@ -0,0 +1,137 @@
+package agentorchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestRetryClonesFailedRun(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": "invalid_request", "message": "bad input"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+
+	run := createTestRun(t, srv, "tnt_retry", `{"input":{"type":"text","text":"hello"},"context":{"locale":"en-US","timezone":"UTC","user_id":"usr_1"},"run_options":{"priority":"high","max_steps":4}}`)
+	failed := waitForStatus(t, srv, "tnt_retry", run.RunID, "completed", "failed")
+	if failed.Status != "failed" || failed.RetriedBy != "" {
+		t.Fatalf("expected failed run without automatic retry, got %s retried_by=%q", failed.Status, failed.RetriedBy)
+	}
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":retry", "tnt_retry", "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 retrying failed run, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp types.RunCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal retry response: %v", err)
+	}
+	retry := resp.Run
+	if retry.RunID == run.RunID || retry.ParentRunID != run.RunID || retry.Attempt != 2 {
+		t.Fatalf("expected new run linked to parent as attempt 2, got %+v", retry)
+	}
+	if retry.Input == nil || retry.Input.Text != "hello" || retry.Context == nil || retry.Context.UserID != "usr_1" ||
+		retry.RunOptions.Priority != "high" || retry.RunOptions.MaxSteps != 4 {
+		t.Fatalf("expected request cloned from parent, got %+v", retry)
+	}
+	if done := waitForStatus(t, srv, "tnt_retry", retry.RunID, "completed", "failed"); done.Status != "completed" {
+		t.Fatalf("expected retry to complete, got %s %+v", done.Status, done.Error)
+	}
+	if parent := waitForStatus(t, srv, "tnt_retry", run.RunID, "failed"); parent.RetriedBy != retry.RunID {
+		t.Fatalf("expected parent to link its retry, got %q", parent.RetriedBy)
+	}
+
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":retry", "tnt_retry", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 retrying a run twice, got %d", rec.Code)
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+retry.RunID+":retry", "tnt_retry", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 retrying a completed run, got %d", rec.Code)
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/runs/"+run.RunID+":retry", "tnt_retry_other", ""); rec.Code == http.StatusCreated {
+		t.Fatalf("expected retry to be tenant scoped")
+	}
+}
+
+func TestAutomaticRetryOnRetryableError(t *testing.T) {
+	t.Setenv("AGENTOS_QUOTA_CONCURRENT_RUNS", "1")
+	var mu sync.Mutex
+	calls := 0
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": "provider_unavailable", "message": "try later", "retryable": true}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+
+	run := createTestRun(t, srv, "tnt_autoretry", `{"input":{"type":"text","text":"hello"},"run_options":{"retry":{"max_attempts":3,"backoff_ms":5}}}`)
+	first := waitForStatus(t, srv, "tnt_autoretry", run.RunID, "completed", "failed")
+	if first.Status != "failed" || first.RetriedBy == "" {
+		t.Fatalf("expected failed run with automatic retry, got %s retried_by=%q", first.Status, first.RetriedBy)
+	}
+	second := waitForStatus(t, srv, "tnt_autoretry", first.RetriedBy, "completed", "failed")
+	if second.Status != "failed" || second.Attempt != 2 || second.ParentRunID != run.RunID || second.RetriedBy == "" {
+		t.Fatalf("expected second attempt to fail and retry again, got %+v", second)
+	}
+	third := waitForStatus(t, srv, "tnt_autoretry", second.RetriedBy, "completed", "failed")
+	if third.Status != "completed" || third.Attempt != 3 {
+		t.Fatalf("expected third attempt to complete, got %s attempt=%d", third.Status, third.Attempt)
+	}
+
+	events := readEvents(t, srv, "tnt_autoretry", "/v1/runs/"+run.RunID+"/events")
+	var scheduled bool
+	for _, ev := range events {
+		if ev.Type == "agentos.run.retry.scheduled" && ev.Payload["retry_run_id"] == first.RetriedBy {
+			scheduled = true
+		}
+	}
+	if !scheduled || events[len(events)-1].Type != "agentos.run.failed" {
+		t.Fatalf("expected retry.scheduled before the terminal event, got %+v", events)
+	}
+
+	// the chain handed one concurrency slot along and released it on completion
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents/agt_test/runs", "tnt_autoretry", `{"input":{"type":"text","text":"again"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected slot released after retry chain, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestShouldAutoRetry(t *testing.T) {
+	policy := &types.RetryPolicy{MaxAttempts: 2}
+	cases := []struct {
+		name string
+		run  types.Run
+		want bool
+	}{
+		{"no policy", types.Run{Error: &types.RunError{Code: "run_timeout"}}, false},
+		{"retryable code", types.Run{RunOptions: types.RunOptions{Retry: policy}, Error: &types.RunError{Code: "run_timeout"}}, true},
+		{"retryable detail", types.Run{RunOptions: types.RunOptions{Retry: policy}, Error: &types.RunError{Code: "x", Details: map[string]any{"retryable": true}}}, true},
+		{"not retryable", types.Run{RunOptions: types.RunOptions{Retry: policy}, Error: &types.RunError{Code: "max_steps_exceeded"}}, false},
+		{"attempts used", types.Run{Attempt: 2, RunOptions: types.RunOptions{Retry: policy}, Error: &types.RunError{Code: "run_timeout"}}, false},
+		{"retry_on", types.Run{RunOptions: types.RunOptions{Retry: &types.RetryPolicy{MaxAttempts: 2, RetryOn: []string{"max_steps_exceeded"}}}, Error: &types.RunError{Code: "max_steps_exceeded"}}, true},
+		{"retry_on excludes", types.Run{RunOptions: types.RunOptions{Retry: &types.RetryPolicy{MaxAttempts: 2, RetryOn: []string{"tool_failed"}}}, Error: &types.RunError{Code: "run_timeout"}}, false},
+	}
+	for _, tc := range cases {
+		if got := shouldAutoRetry(tc.run); got != tc.want {
+			t.Errorf("%s: shouldAutoRetry = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package agentorchestrator
+
+import (
+	"context"
+	"sync"
+)
+
+// priorityWeights sets each priority's share of dispatch: while flows of every priority
+// are backlogged, a high flow is dispatched four times as often as a low one.
+var priorityWeights = map[string]float64{
+	"low":    1,
+	"normal": 2,
+	"high":   4,
+}
+
+func priorityWeight(priority string) float64 {
+	if w, ok := priorityWeights[priority]; ok {
+		return w
+	}
+	return priorityWeights["normal"]
+}
+
+// runScheduler orders queued runs with weighted fair queuing over (tenant, priority)
+// flows. Each run is tagged with a virtual finish time of max(now, flow's last tag) +
+// 1/weight and the smallest tag is dispatched first, so a tenant flooding the queue only
+// stretches its own flow: another tenant's newly queued run is tagged relative to the
+// current virtual time and is dispatched after at most a few of the flood's runs.
+type runScheduler struct {
+	mu       sync.Mutex
+	flows    map[flowKey]*schedFlow
+	vtime    float64
+	seq      uint64
+	size     int
+	capacity int
+	// queued holds the runs in the scheduler, so pushing a run twice queues it once.
+	queued map[runRef]struct{}
+
+	// ready holds one token per queued run so workers can block on it alongside ctx.
+	ready chan struct{}
+}
+
+type flowKey struct {
+	TenantID string
+	Priority string
+}
+
+type schedFlow struct {
+	items   []schedItem
+	lastTag float64
+}
+
+type schedItem struct {
+	ref runRef
+	tag float64
+	seq uint64
+}
+
+func newRunScheduler(capacity int) *runScheduler {
+	return &runScheduler{
+		flows:    make(map[flowKey]*schedFlow),
+		capacity: capacity,
+		queued:   make(map[runRef]struct{}),
+		ready:    make(chan struct{}, capacity),
+	}
+}
+
+// Push queues a run. It never blocks and returns false when the scheduler is full. A run
+// that is already queued keeps its place.
+func (s *runScheduler) Push(ref runRef, priority string) bool {
+	if _, ok := priorityWeights[priority]; !ok {
+		priority = "normal"
+	}
+	s.mu.Lock()
+	if _, ok := s.queued[ref]; ok {
+		s.mu.Unlock()
+		return true
+	}
+	if s.size >= s.capacity {
+		s.mu.Unlock()
+		return false
+	}
+	key := flowKey{TenantID: ref.TenantID, Priority: priority}
+	flow, ok := s.flows[key]
+	if !ok {
+		flow = &schedFlow{}
+		s.flows[key] = flow
+	}
+	start := s.vtime
+	if flow.lastTag > start {
+		start = flow.lastTag
+	}
+	flow.lastTag = start + 1/priorityWeight(priority)
+	s.seq++
+	flow.items = append(flow.items, schedItem{ref: ref, tag: flow.lastTag, seq: s.seq})
+	s.size++
+	s.queued[ref] = struct{}{}
+	s.mu.Unlock()
+
+	s.ready <- struct{}{}
+	return true
+}
+
+// Pop blocks until a run is available or ctx is done.
+func (s *runScheduler) Pop(ctx context.Context) (runRef, bool) {
+	select {
+	case <-ctx.Done():
+		return runRef{}, false
+	case <-s.ready:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var bestKey flowKey
+	var best *schedFlow
+	for key, flow := range s.flows {
+		head := flow.items[0]
+		if best == nil || head.tag < best.items[0].tag || (head.tag == best.items[0].tag && head.seq < best.items[0].seq) {
+			bestKey, best = key, flow
+		}
+	}
+	item := best.items[0]
+	best.items = best.items[1:]
+	if len(best.items) == 0 {
+		// an empty flow's last tag is <= vtime, so dropping it loses nothing
+		delete(s.flows, bestKey)
+	}
+	s.vtime = item.tag
+	s.size--
+	delete(s.queued, item.ref)
+	return item.ref, true
+}
+
+// Len returns the number of queued runs.
+func (s *runScheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
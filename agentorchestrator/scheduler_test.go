@@ -0,0 +1,90 @@
+package agentorchestrator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func popTenants(t *testing.T, s *runScheduler, n int) []string {
+	t.Helper()
+	var tenants []string
+	for i := 0; i < n; i++ {
+		ref, ok := s.Pop(context.Background())
+		if !ok {
+			t.Fatalf("pop %d: scheduler empty", i)
+		}
+		tenants = append(tenants, ref.TenantID)
+	}
+	return tenants
+}
+
+func TestSchedulerFloodDoesNotStarveOtherTenants(t *testing.T) {
+	s := newRunScheduler(100)
+	for i := 0; i < 20; i++ {
+		s.Push(runRef{TenantID: "tnt_batch", RunID: fmt.Sprintf("run_%d", i)}, "low")
+	}
+	s.Push(runRef{TenantID: "tnt_interactive", RunID: "run_hi"}, "high")
+	s.Push(runRef{TenantID: "tnt_interactive", RunID: "run_norm"}, "normal")
+
+	got := popTenants(t, s, 3)
+	if got[0] != "tnt_interactive" || got[1] != "tnt_interactive" {
+		t.Fatalf("expected interactive runs ahead of the backlog, got %v", got)
+	}
+	if s.Len() != 19 {
+		t.Fatalf("expected 19 runs left, got %d", s.Len())
+	}
+}
+
+func TestSchedulerSharesFairlyAndByPriorityWeight(t *testing.T) {
+	s := newRunScheduler(100)
+	for i := 0; i < 10; i++ {
+		s.Push(runRef{TenantID: "tnt_a", RunID: fmt.Sprintf("a_%d", i)}, "normal")
+	}
+	for i := 0; i < 10; i++ {
+		s.Push(runRef{TenantID: "tnt_b", RunID: fmt.Sprintf("b_%d", i)}, "normal")
+	}
+	counts := map[string]int{}
+	for _, tenant := range popTenants(t, s, 10) {
+		counts[tenant]++
+	}
+	if counts["tnt_a"] != 5 || counts["tnt_b"] != 5 {
+		t.Fatalf("expected equal share for equal priorities, got %v", counts)
+	}
+
+	s = newRunScheduler(100)
+	for i := 0; i < 8; i++ {
+		s.Push(runRef{TenantID: "tnt_low", RunID: fmt.Sprintf("l_%d", i)}, "low")
+		s.Push(runRef{TenantID: "tnt_high", RunID: fmt.Sprintf("h_%d", i)}, "high")
+	}
+	counts = map[string]int{}
+	for _, tenant := range popTenants(t, s, 5) {
+		counts[tenant]++
+	}
+	if counts["tnt_high"] != 4 || counts["tnt_low"] != 1 {
+		t.Fatalf("expected a 4:1 share for high vs low, got %v", counts)
+	}
+}
+
+func TestSchedulerIsBounded(t *testing.T) {
+	s := newRunScheduler(2)
+	if !s.Push(runRef{TenantID: "tnt_a", RunID: "r1"}, "") || !s.Push(runRef{TenantID: "tnt_a", RunID: "r2"}, "bogus") {
+		t.Fatalf("expected pushes within capacity to succeed")
+	}
+	if s.Push(runRef{TenantID: "tnt_b", RunID: "r3"}, "high") {
+		t.Fatalf("expected push beyond capacity to fail")
+	}
+	if !s.Push(runRef{TenantID: "tnt_a", RunID: "r2"}, "") || s.Len() != 2 {
+		t.Fatalf("expected a run already queued to be accepted once, got len %d", s.Len())
+	}
+	if ref, _ := s.Pop(context.Background()); ref.RunID != "r1" {
+		t.Fatalf("expected FIFO within a flow, got %s", ref.RunID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Pop(context.Background())
+	if _, ok := s.Pop(ctx); ok {
+		t.Fatalf("expected Pop to return when ctx is done")
+	}
+}
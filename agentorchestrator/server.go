@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/audit"
@@ -24,11 +26,20 @@ import (
 type Server struct {
 	version string
 
-	runs    storage.RunStore
-	agents  storage.AgentStore
-	tenants *tenants.Store
-	limiter *quota.Limiter
-	audit   audit.Logger
+	runs     storage.RunStore
+	events   storage.EventStore
+	agents   storage.AgentStore
+	sessions storage.SessionStore
+	webhooks storage.WebhookStore
+	tenants  *tenants.Store
+	limiter  quota.Limiter
+	audit    audit.Logger
+	exec     *executor
+
+	// agentMu serializes agent read-modify-write updates.
+	agentMu sync.Mutex
+
+	sseKeepalive time.Duration
 }
 
 func New(version string) (*Server, error) {
@@ -36,30 +47,69 @@ func New(version string) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	eventStore, err := storage.NewEventStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	agentStore, err := storage.NewAgentStoreFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	tenantStore := tenants.NewStore()
+	sessionStore, err := storage.NewSessionStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	webhookStore, err := storage.NewWebhookStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	tenantStore, err := tenants.NewStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	defaultTenant := auth.DefaultTenant()
-	if defaultTenant != "" {
-		tenantStore.EnsureDefault(defaultTenant)
+	if err := tenantStore.EnsureDefault(defaultTenant); err != nil {
+		return nil, err
+	}
+	limits, err := quota.TenantLimitsFromEnv(tenantStore.Get, quota.RunQuotaKeys, "AGENTOS_QUOTA_RUN_CREATE_QPS", "AGENTOS_QUOTA_CONCURRENT_RUNS", 10, 25)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := quota.NewFromEnv("agent-orchestrator", limits)
+	if err != nil {
+		return nil, err
 	}
 	srv := &Server{
-		version: version,
-		runs:    runStore,
-		agents:  agentStore,
-		tenants: tenantStore,
-		limiter: quota.NewFromEnv("AGENTOS_QUOTA_RUN_CREATE_QPS", "AGENTOS_QUOTA_CONCURRENT_RUNS", 10, 25),
-		audit:   audit.NewFromEnv(),
+		version:  version,
+		runs:     runStore,
+		events:   eventStore,
+		agents:   agentStore,
+		sessions: sessionStore,
+		webhooks: webhookStore,
+		tenants:  tenantStore,
+		limiter:  limiter,
+		audit:    audit.NewFromEnv(),
+		exec:     newExecutorFromEnv(runStore, eventStore, sessionStore, webhookStore, limiter),
+
+		sseKeepalive: time.Duration(envInt("AGENTOS_SSE_KEEPALIVE_MS", 15000)) * time.Millisecond,
 	}
 	// Seed demo agent for default tenant
 	if defaultTenant != "" {
 		srv.seedDemoAgent(defaultTenant)
 	}
+	if err := srv.exec.recoverRuns(context.Background()); err != nil {
+		return nil, err
+	}
 	return srv, nil
 }
 
+// Start launches the background run executor and the tenant store refresh. It returns
+// immediately; both stop when ctx is canceled.
+func (s *Server) Start(ctx context.Context) {
+	s.exec.Start(ctx)
+	s.tenants.WatchFromEnv(ctx)
+}
+
 func (s *Server) seedDemoAgent(tenantID string) {
 	now := time.Now().UTC().Format(time.RFC3339)
 	demoAgent := types.Agent{
@@ -67,20 +117,28 @@ func (s *Server) seedDemoAgent(tenantID string) {
 		TenantID:    tenantID,
 		Name:        "Demo Agent",
 		Description: "Sample agent for validation",
-		Version:     "1.0",
+		Version:     "1",
 		Status:      "active",
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 	// Ignore error if agent already exists
-	_ = s.agents.Create(context.Background(), demoAgent)
+	if err := s.agents.Create(context.Background(), demoAgent); err == nil {
+		_ = s.agents.CreateVersion(context.Background(), agentVersionOf(demoAgent, ""))
+	}
 }
 
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/v1/health", s.handleHealth)
-	mux.HandleFunc("/v1/agents/", s.handleAgents) // /v1/agents/{agent_id}/runs
-	mux.HandleFunc("/v1/runs/", s.handleRuns)     // /v1/runs/{run_id} and /v1/runs/{run_id}/events
+	mux.HandleFunc("/v1/agents", s.handleAgents)
+	mux.HandleFunc("/v1/agents/", s.handleAgents) // /v1/agents/{agent_id} and /v1/agents/{agent_id}/runs
+	mux.HandleFunc("/v1/runs", s.handleRuns)
+	mux.HandleFunc("/v1/runs/", s.handleRuns) // /v1/runs/{run_id} and /v1/runs/{run_id}/events
+	mux.HandleFunc("/v1/sessions", s.handleSessions)
+	mux.HandleFunc("/v1/sessions/", s.handleSessions) // /v1/sessions/{session_id}
+	mux.HandleFunc("/v1/webhooks", s.handleWebhooks)
+	mux.HandleFunc("/v1/webhooks/", s.handleWebhooks) // /v1/webhooks/{webhook_id}
 	mux.HandleFunc("/v1/admin/tenants", s.handleTenants)
 	mux.HandleFunc("/v1/admin/tenants/", s.handleTenants)
 	mux.Handle("/metrics", middleware.ProtectMetrics(metrics.Handler()))
@@ -110,24 +168,58 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := strings.TrimPrefix(r.URL.Path, "/v1/agents/")
+	path := strings.TrimPrefix(r.URL.Path, "/v1/agents")
 	path = strings.Trim(path, "/")
 
-	// GET /v1/agents - List agents
 	if path == "" {
-		if r.Method != http.MethodGet {
+		switch r.Method {
+		case http.MethodGet:
+			// GET /v1/agents - List agents
+			s.handleAgentList(w, r, tenantID)
+		case http.MethodPost:
+			// POST /v1/agents - Register agent
+			s.handleAgentCreate(w, r, tenantID, ac)
+		default:
 			httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
-			return
 		}
-		s.handleAgentList(w, r, tenantID)
 		return
 	}
 
 	parts := strings.Split(path, "/")
 
-	// GET /v1/agents/{agent_id} - Get agent metadata
-	if len(parts) == 1 && r.Method == http.MethodGet {
-		s.handleAgentGet(w, r, tenantID, parts[0])
+	// GET /v1/agents/{agent_id}/versions - List agent versions
+	if len(parts) == 2 && parts[1] == "versions" && r.Method == http.MethodGet {
+		s.handleAgentVersionList(w, r, tenantID, parts[0])
+		return
+	}
+
+	if len(parts) == 3 && parts[1] == "versions" {
+		// POST /v1/agents/{agent_id}/versions/{version}:rollback - Roll back to a version
+		if version, ok := strings.CutSuffix(parts[2], ":rollback"); ok && r.Method == http.MethodPost {
+			s.handleAgentRollback(w, r, tenantID, parts[0], version, ac)
+			return
+		}
+		// GET /v1/agents/{agent_id}/versions/{version} - Get agent version
+		if r.Method == http.MethodGet {
+			s.handleAgentVersionGet(w, r, tenantID, parts[0], parts[2])
+			return
+		}
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			// GET /v1/agents/{agent_id} - Get agent metadata
+			s.handleAgentGet(w, r, tenantID, parts[0])
+		case http.MethodPut:
+			// PUT /v1/agents/{agent_id} - Update agent metadata and status
+			s.handleAgentUpdate(w, r, tenantID, parts[0], ac)
+		case http.MethodDelete:
+			// DELETE /v1/agents/{agent_id} - Retire agent
+			s.handleAgentRetire(w, r, tenantID, parts[0], ac)
+		default:
+			httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+		}
 		return
 	}
 
@@ -137,6 +229,12 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// GET /v1/agents/{agent_id}/runs - List the agent's runs
+	if len(parts) == 2 && parts[1] == "runs" && r.Method == http.MethodGet {
+		s.handleRunList(w, r, tenantID, parts[0])
+		return
+	}
+
 	httpx.Error(w, http.StatusNotFound, "not_found", "not found", httpx.CorrelationID(r), false)
 }
 
@@ -169,7 +267,9 @@ func (s *Server) handleAgentGet(w http.ResponseWriter, r *http.Request, tenantID
 }
 
 func (s *Server) handleRunCreate(w http.ResponseWriter, r *http.Request, tenantID, agentID string, ac auth.AuthContext) {
-	if !s.limiter.AllowQPS(tenantID) {
+	qps := s.limiter.AllowQPS(tenantID)
+	quota.SetHeaders(w.Header(), qps)
+	if !qps.Allowed {
 		metrics.IncQuotaDenied("agent-orchestrator", "runs_create_qps")
 		httpx.Error(w, http.StatusTooManyRequests, "quota_exceeded", "run create QPS exceeded", httpx.CorrelationID(r), true)
 		s.audit.Log(audit.Entry{
@@ -179,23 +279,46 @@ func (s *Server) handleRunCreate(w http.ResponseWriter, r *http.Request, tenantI
 		})
 		return
 	}
-	if !s.limiter.TryIncConcurrent(tenantID) {
-		metrics.IncQuotaDenied("agent-orchestrator", "runs_concurrency")
-		httpx.Error(w, http.StatusTooManyRequests, "quota_exceeded", "concurrent runs exceeded", httpx.CorrelationID(r), true)
-		s.audit.Log(audit.Entry{
-			TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "runs.create", Resource: "agent-orchestrator", Outcome: "denied",
-			CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
-			Meta: map[string]any{"reason": "concurrent_exceeded"},
-		})
+	agent, err := s.runnableAgent(r, tenantID, agentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAgentNotFound):
+			httpx.Error(w, http.StatusNotFound, "not_found", "agent not found", httpx.CorrelationID(r), false)
+		case errors.Is(err, errAgentRetired):
+			httpx.Error(w, http.StatusConflict, "agent_retired", "agent is retired", httpx.CorrelationID(r), false)
+		default:
+			httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to load agent", httpx.CorrelationID(r), true)
+		}
 		return
 	}
 
 	var req types.RunCreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.limiter.DecConcurrent(tenantID)
 		httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
 		return
 	}
+	def, agentVersion := agent.Definition, agent.Version
+	if req.AgentVersion != "" && req.AgentVersion != agent.Version {
+		pinned, found, err := s.agents.GetVersion(r.Context(), tenantID, agentID, req.AgentVersion)
+		if err != nil {
+			httpx.Error(w, http.StatusInternalServerError, "agent_lookup_failed", "failed to load agent version", httpx.CorrelationID(r), true)
+			return
+		}
+		if !found {
+			httpx.Error(w, http.StatusNotFound, "not_found", "agent version not found", httpx.CorrelationID(r), false)
+			return
+		}
+		def, agentVersion = pinned.Definition, pinned.Version
+	}
+	cfg, msg, ok := resolveRunConfig(def, req)
+	if !ok {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", msg, httpx.CorrelationID(r), false)
+		return
+	}
+	if req.Context.SessionID != "" && !sessionIDPattern.MatchString(req.Context.SessionID) {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", "context.session_id must match "+sessionIDPattern.String(), httpx.CorrelationID(r), false)
+		return
+	}
 
 	// Check idempotency key if provided
 	if req.IdempotencyKey != "" {
@@ -205,7 +328,6 @@ func (s *Server) handleRunCreate(w http.ResponseWriter, r *http.Request, tenantI
 			// In production, this should use structured logging
 		} else if found {
 			// Return existing run with 200 OK (not 201 Created)
-			s.limiter.DecConcurrent(tenantID)
 			resp := types.RunCreateResponse{Run: existingRun, CorrelationID: httpx.CorrelationID(r)}
 			httpx.JSON(w, http.StatusOK, resp)
 			return
@@ -215,19 +337,42 @@ func (s *Server) handleRunCreate(w http.ResponseWriter, r *http.Request, tenantI
 	now := time.Now().UTC().Format(time.RFC3339)
 	runID := id.New("run")
 
+	// The slot is owned by the run from here on and released when it reaches a terminal state.
+	if !s.limiter.AcquireRunSlot(tenantID, runID) {
+		metrics.IncQuotaDenied("agent-orchestrator", "runs_concurrency")
+		quota.SetRetryAfter(w.Header(), quota.SlotRetryAfter)
+		httpx.Error(w, http.StatusTooManyRequests, "quota_exceeded", "concurrent runs exceeded", httpx.CorrelationID(r), true)
+		s.audit.Log(audit.Entry{
+			TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "runs.create", Resource: "agent-orchestrator", Outcome: "denied",
+			CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+			Meta: map[string]any{"reason": "concurrent_exceeded"},
+		})
+		return
+	}
+
 	run := types.Run{
 		TenantID:       tenantID,
 		AgentID:        agentID,
+		AgentVersion:   agentVersion,
 		RunID:          runID,
 		Status:         "queued",
 		CreatedAt:      now,
 		EventsURL:      "/v1/runs/" + runID + "/events",
-		RunOptions:     req.RunOptions,
+		Input:          &req.Input,
+		ModelID:        cfg.ModelID,
+		Instructions:   cfg.Instructions,
+		RunOptions:     cfg.Options,
 		IdempotencyKey: req.IdempotencyKey,
+		Context:        &req.Context,
+		SessionID:      req.Context.SessionID,
+		Attempt:        1,
+	}
+	if len(cfg.Tools) > 0 {
+		run.Tooling = &types.Tooling{Tools: cfg.Tools}
 	}
 
 	if err := s.runs.Create(r.Context(), run); err != nil {
-		s.limiter.DecConcurrent(tenantID)
+		s.limiter.ReleaseRunSlot(tenantID, runID)
 		code := http.StatusInternalServerError
 		errCode := "run_persist_failed"
 		retryable := true
@@ -250,6 +395,10 @@ func (s *Server) handleRunCreate(w http.ResponseWriter, r *http.Request, tenantI
 		Meta: map[string]any{"agent_id": agentID},
 	})
 
+	s.exec.emit(r.Context(), run, "", "agentos.run.created", map[string]any{"status": run.Status})
+
+	_ = s.exec.Enqueue(tenantID, runID, run.RunOptions.Priority)
+
 	resp := types.RunCreateResponse{Run: run, CorrelationID: httpx.CorrelationID(r)}
 	httpx.JSON(w, http.StatusCreated, resp)
 }
@@ -269,10 +418,15 @@ func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := strings.TrimPrefix(r.URL.Path, "/v1/runs/")
+	path := strings.TrimPrefix(r.URL.Path, "/v1/runs")
 	parts := strings.Split(strings.Trim(path, "/"), "/")
 	if len(parts) == 0 || parts[0] == "" {
-		httpx.Error(w, http.StatusNotFound, "not_found", "not found", httpx.CorrelationID(r), false)
+		// GET /v1/runs - List runs
+		if r.Method != http.MethodGet {
+			httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+			return
+		}
+		s.handleRunList(w, r, tenantID, "")
 		return
 	}
 	runID := parts[0]
@@ -284,6 +438,22 @@ func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check for :approve / :reject actions on runs waiting for input
+	if strings.HasSuffix(runID, ":approve") {
+		s.handleRunDecision(w, r, tenantID, strings.TrimSuffix(runID, ":approve"), ac, true)
+		return
+	}
+	if strings.HasSuffix(runID, ":reject") {
+		s.handleRunDecision(w, r, tenantID, strings.TrimSuffix(runID, ":reject"), ac, false)
+		return
+	}
+
+	// Check for :retry action on failed runs
+	if strings.HasSuffix(runID, ":retry") {
+		s.handleRetry(w, r, tenantID, strings.TrimSuffix(runID, ":retry"), ac)
+		return
+	}
+
 	if len(parts) == 2 && parts[1] == "events" {
 		s.handleEvents(w, r, tenantID, runID)
 		return
@@ -304,35 +474,72 @@ func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// For scaffold: auto-progress and auto-complete after ~5s
-	updated := false
-	if created, err := time.Parse(time.RFC3339, run.CreatedAt); err == nil {
-		age := time.Since(created)
-		if run.Status == "queued" && age > 1*time.Second {
-			run.Status = "running"
-			if run.StartedAt == "" {
-				run.StartedAt = time.Now().UTC().Format(time.RFC3339)
+	resp := types.RunGetResponse{Run: run, CorrelationID: httpx.CorrelationID(r)}
+	httpx.JSON(w, http.StatusOK, resp)
+}
+
+const (
+	defaultRunListLimit = 50
+	maxRunListLimit     = 200
+)
+
+// handleRunList serves GET /v1/runs and GET /v1/agents/{agent_id}/runs. agentID from the
+// path takes precedence over the agent_id query filter.
+func (s *Server) handleRunList(w http.ResponseWriter, r *http.Request, tenantID, agentID string) {
+	q := r.URL.Query()
+	filter := storage.RunFilter{
+		AgentID:        q.Get("agent_id"),
+		IdempotencyKey: q.Get("idempotency_key"),
+		Cursor:         q.Get("cursor"),
+		Limit:          defaultRunListLimit,
+	}
+	if agentID != "" {
+		filter.AgentID = agentID
+	}
+	for _, raw := range q["status"] {
+		for _, st := range strings.Split(raw, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				filter.Statuses = append(filter.Statuses, st)
 			}
-			updated = true
 		}
-		if (run.Status == "running" || run.Status == "queued") && age > 5*time.Second {
-			run.Status = "completed"
-			run.CompletedAt = time.Now().UTC().Format(time.RFC3339)
-			s.limiter.DecConcurrent(tenantID)
-			run.Output = &types.RunOutput{Type: "text", Text: "stub completed output"}
-			updated = true
+	}
+	for param, dst := range map[string]*time.Time{"created_after": &filter.CreatedAfter, "created_before": &filter.CreatedBefore} {
+		raw := q.Get(param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", param+" must be an RFC3339 timestamp", httpx.CorrelationID(r), false)
+			return
 		}
+		*dst = t
 	}
-
-	if updated {
-		if err := s.runs.Save(r.Context(), run); err != nil {
-			httpx.Error(w, http.StatusInternalServerError, "run_persist_failed", "failed to persist run update", httpx.CorrelationID(r), true)
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", "limit must be a positive integer", httpx.CorrelationID(r), false)
 			return
 		}
+		if n > maxRunListLimit {
+			n = maxRunListLimit
+		}
+		filter.Limit = n
 	}
 
-	resp := types.RunGetResponse{Run: run, CorrelationID: httpx.CorrelationID(r)}
-	httpx.JSON(w, http.StatusOK, resp)
+	runs, next, err := s.runs.List(r.Context(), tenantID, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", "invalid cursor", httpx.CorrelationID(r), false)
+			return
+		}
+		httpx.Error(w, http.StatusInternalServerError, "run_list_failed", "failed to list runs", httpx.CorrelationID(r), true)
+		return
+	}
+	if runs == nil {
+		runs = []types.Run{}
+	}
+	httpx.JSON(w, http.StatusOK, types.RunListResponse{Runs: runs, NextCursor: next, CorrelationID: httpx.CorrelationID(r)})
 }
 
 func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, tenantID, runID string) {
@@ -341,7 +548,7 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, tenantID,
 		return
 	}
 
-	run, ok, err := s.runs.Get(r.Context(), tenantID, runID)
+	_, ok, err := s.runs.Get(r.Context(), tenantID, runID)
 	if err != nil {
 		httpx.Error(w, http.StatusInternalServerError, "run_lookup_failed", "failed to load run", httpx.CorrelationID(r), true)
 		return
@@ -351,79 +558,120 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, tenantID,
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-
 	flusher, okf := w.(http.Flusher)
 	if !okf {
 		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
 		return
 	}
 
-	env := types.EventEnvelope{
-		Event: types.Event{
-			EventID:  id.New("evt"),
-			Sequence: 1,
-			Time:     time.Now().UTC().Format(time.RFC3339),
-			Type:     "agentos.run.step.completed",
-			TenantID: run.TenantID,
-			AgentID:  run.AgentID,
-			RunID:    run.RunID,
-			StepID:   "step_1",
-			Trace:    types.TraceContext{Traceparent: "00-00000000000000000000000000000000-0000000000000000-01"},
-			Payload:  map[string]any{"status": "ok"},
-		},
-	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
 
-	b, _ := json.Marshal(env)
+	ref := runRef{TenantID: tenantID, RunID: runID}
+	cursor := sequenceCursor(r)
+	keepalive := time.NewTicker(s.sseKeepalive)
+	defer keepalive.Stop()
 	bw := bufio.NewWriter(w)
-	_, _ = bw.WriteString("event: agentos.event\n")
-	_, _ = bw.WriteString("data: " + string(b) + "\n\n")
-	_ = bw.Flush()
-	flusher.Flush()
-}
+	closing := false
+	// Subscribe before the first listing so appends in between are not missed.
+	wake, unsubscribe := s.exec.hub.subscribe(ref)
+	defer unsubscribe()
 
-func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request, tenantID, runID string, ac auth.AuthContext) {
-	if r.Method != http.MethodPost {
-		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
-		return
+	for {
+		events, err := s.events.List(r.Context(), tenantID, runID, cursor)
+		if err != nil {
+			return
+		}
+		done := false
+		for _, ev := range events {
+			b, _ := json.Marshal(types.EventEnvelope{Event: ev})
+			_, _ = bw.WriteString("id: " + strconv.Itoa(ev.Sequence) + "\n")
+			_, _ = bw.WriteString("event: agentos.event\n")
+			_, _ = bw.WriteString("data: " + string(b) + "\n\n")
+			cursor = ev.Sequence
+			if isTerminalEventType(ev.Type) {
+				done = true
+			}
+		}
+		if len(events) > 0 {
+			_ = bw.Flush()
+			flusher.Flush()
+		}
+		if done || closing {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		case <-keepalive.C:
+			_, _ = bw.WriteString(": keepalive\n\n")
+			_ = bw.Flush()
+			flusher.Flush()
+			// Runs that reached a terminal status without a terminal event (e.g. finished
+			// before the event log existed) get one final drain, then the stream closes.
+			if run, ok, err := s.runs.Get(r.Context(), tenantID, runID); err != nil || !ok || isTerminalStatus(run.Status) {
+				closing = true
+			}
+		}
 	}
+}
 
-	run, ok, err := s.runs.Get(r.Context(), tenantID, runID)
-	if err != nil {
-		httpx.Error(w, http.StatusInternalServerError, "run_lookup_failed", "failed to load run", httpx.CorrelationID(r), true)
-		return
+// sequenceCursor reads the exclusive replay cursor. A Last-Event-ID header (sent by SSE
+// clients on reconnect) wins over from_sequence (used by federation) and after_sequence
+// (OpenAPI); invalid values replay from the start.
+func sequenceCursor(r *http.Request) int {
+	raw := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if raw == "" {
+		q := r.URL.Query()
+		raw = q.Get("from_sequence")
+		if raw == "" {
+			raw = q.Get("after_sequence")
+		}
 	}
-	if !ok {
-		// Tenant isolation: return 404 (not 403) for runs not in this tenant
-		httpx.Error(w, http.StatusNotFound, "not_found", "run not found", httpx.CorrelationID(r), false)
-		return
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0
 	}
+	return n
+}
 
-	// State transition validation
-	switch run.Status {
-	case "completed", "failed", "canceled":
-		// Cannot cancel from terminal states
-		httpx.Error(w, http.StatusConflict, "invalid_state_transition", "cannot cancel run in "+run.Status+" state", httpx.CorrelationID(r), false)
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request, tenantID, runID string, ac auth.AuthContext) {
+	if r.Method != http.MethodPost {
+		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
 		return
-	case "queued", "running":
-		// Can cancel from these states
-	default:
-		// Unknown state, allow cancellation
 	}
 
-	// Update run to canceled state
-	run.Status = "canceled"
-	run.CompletedAt = time.Now().UTC().Format(time.RFC3339)
-
-	if err := s.runs.Save(r.Context(), run); err != nil {
-		httpx.Error(w, http.StatusInternalServerError, "run_persist_failed", "failed to persist run cancellation", httpx.CorrelationID(r), true)
+	run, err := s.exec.updateRun(r.Context(), tenantID, runID, func(run *types.Run) error {
+		// State transition validation: cannot cancel from terminal states
+		if isTerminalStatus(run.Status) {
+			return errInvalidStateTransition
+		}
+		run.Status = "canceled"
+		run.CompletedAt = time.Now().UTC().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errRunNotFound):
+			// Tenant isolation: return 404 (not 403) for runs not in this tenant
+			httpx.Error(w, http.StatusNotFound, "not_found", "run not found", httpx.CorrelationID(r), false)
+		case errors.Is(err, errInvalidStateTransition):
+			httpx.Error(w, http.StatusConflict, "invalid_state_transition", "cannot cancel run in "+run.Status+" state", httpx.CorrelationID(r), false)
+		default:
+			httpx.Error(w, http.StatusInternalServerError, "run_persist_failed", "failed to persist run cancellation", httpx.CorrelationID(r), true)
+		}
 		return
 	}
 
-	// Decrement concurrent run quota
-	s.limiter.DecConcurrent(tenantID)
+	// Stop in-flight execution and release the concurrent run quota
+	s.exec.Abort(tenantID, runID)
+	s.limiter.ReleaseRunSlot(tenantID, runID)
+	s.exec.emit(r.Context(), run, "", "agentos.run.canceled", map[string]any{"status": run.Status})
 
 	// Audit log
 	s.audit.Log(audit.Entry{
@@ -456,14 +704,12 @@ func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
 				httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
 				return
 			}
+			if err := quota.ValidateQuotas(t.Quotas); err != nil {
+				httpx.Error(w, http.StatusBadRequest, "invalid_request", err.Error(), httpx.CorrelationID(r), false)
+				return
+			}
 			if err := s.tenants.Create(t); err != nil {
-				code := http.StatusBadRequest
-				errCode := "invalid_request"
-				if errors.Is(err, tenants.ErrTenantExists) {
-					code = http.StatusConflict
-					errCode = "conflict"
-				}
-				httpx.Error(w, code, errCode, err.Error(), httpx.CorrelationID(r), false)
+				writeTenantError(w, r, err)
 				return
 			}
 			s.audit.Log(audit.Entry{
@@ -478,6 +724,15 @@ func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tenantID := path
+	if before, sub, found := strings.Cut(path, "/"); found {
+		// GET /v1/admin/tenants/{tenant_id}/webhooks/deliveries
+		if sub != "webhooks/deliveries" {
+			httpx.Error(w, http.StatusNotFound, "not_found", "not found", httpx.CorrelationID(r), false)
+			return
+		}
+		s.handleWebhookDeliveries(w, r, before)
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
 		if t, ok := s.tenants.Get(tenantID); ok {
@@ -491,15 +746,13 @@ func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
 			httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
 			return
 		}
+		if err := quota.ValidateQuotas(t.Quotas); err != nil {
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", err.Error(), httpx.CorrelationID(r), false)
+			return
+		}
 		updated, err := s.tenants.Update(tenantID, t)
 		if err != nil {
-			code := http.StatusBadRequest
-			errCode := "invalid_request"
-			if errors.Is(err, tenants.ErrNotFound) {
-				code = http.StatusNotFound
-				errCode = "not_found"
-			}
-			httpx.Error(w, code, errCode, err.Error(), httpx.CorrelationID(r), false)
+			writeTenantError(w, r, err)
 			return
 		}
 		s.audit.Log(audit.Entry{
@@ -509,13 +762,7 @@ func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
 		httpx.JSON(w, http.StatusOK, map[string]any{"tenant": updated, "correlation_id": httpx.CorrelationID(r)})
 	case http.MethodDelete:
 		if _, err := s.tenants.Delete(tenantID); err != nil {
-			code := http.StatusBadRequest
-			errCode := "invalid_request"
-			if errors.Is(err, tenants.ErrNotFound) {
-				code = http.StatusNotFound
-				errCode = "not_found"
-			}
-			httpx.Error(w, code, errCode, err.Error(), httpx.CorrelationID(r), false)
+			writeTenantError(w, r, err)
 			return
 		}
 		s.audit.Log(audit.Entry{
@@ -528,6 +775,21 @@ func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// writeTenantError maps tenant store errors to API errors; anything else is a failure
+// of the tenant store backend.
+func writeTenantError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, tenants.ErrTenantExists):
+		httpx.Error(w, http.StatusConflict, "conflict", err.Error(), httpx.CorrelationID(r), false)
+	case errors.Is(err, tenants.ErrNotFound):
+		httpx.Error(w, http.StatusNotFound, "not_found", err.Error(), httpx.CorrelationID(r), false)
+	case errors.Is(err, tenants.ErrInvalidTenant), errors.Is(err, tenants.ErrDefaultTenant):
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", err.Error(), httpx.CorrelationID(r), false)
+	default:
+		httpx.Error(w, http.StatusInternalServerError, "tenant_persist_failed", "failed to persist tenant", httpx.CorrelationID(r), true)
+	}
+}
+
 func resolveTenant(w http.ResponseWriter, r *http.Request, ac auth.AuthContext) (string, bool) {
 	tenantID, err := auth.RequireTenant(ac)
 	if err != nil {
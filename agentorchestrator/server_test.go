@@ -6,17 +6,48 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
 )
 
-func TestCancelQueuedRunSucceeds(t *testing.T) {
+// seedAgent registers an active agent directly in the server's agent store.
+func seedAgent(t *testing.T, srv *Server, tenantID, agentID string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	agent := types.Agent{AgentID: agentID, TenantID: tenantID, Name: agentID, Version: "1", Status: "active", CreatedAt: now, UpdatedAt: now}
+	if err := srv.agents.Save(context.Background(), agent); err != nil {
+		t.Fatalf("seed agent %s: %v", agentID, err)
+	}
+}
+
+// newTestServer returns an unstarted server whose stores live in a temporary directory.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AGENTOS_RUN_STORE_FILE", filepath.Join(dir, "runs.json"))
+	t.Setenv("AGENTOS_EVENT_STORE_DIR", filepath.Join(dir, "events"))
+	t.Setenv("AGENTOS_AGENT_STORE_DIR", filepath.Join(dir, "agents"))
+	t.Setenv("AGENTOS_SESSION_STORE_DIR", filepath.Join(dir, "sessions"))
+	t.Setenv("AGENTOS_WEBHOOK_STORE_DIR", filepath.Join(dir, "webhooks"))
+	t.Setenv("AGENTOS_TENANT_STORE_FILE", filepath.Join(dir, "tenants.json"))
+	t.Setenv("AGENTOS_AUDIT_SINK", "file:"+filepath.Join(dir, "audit.log"))
+	// tool and webhook test servers listen on loopback
+	t.Setenv("AGENTOS_EGRESS_ALLOW_PRIVATE", "1")
+
 	srv, err := New("test")
 	if err != nil {
 		t.Fatalf("New server error: %v", err)
 	}
+	return srv
+}
+
+func TestCancelQueuedRunSucceeds(t *testing.T) {
+	srv := newTestServer(t)
 
 	// Create a tenant first (requires tenants:admin scope)
 	createTenantReq := httptest.NewRequest(http.MethodPost, "/v1/admin/tenants", bytes.NewBufferString(`{"tenant_id":"tnt_test"}`))
@@ -26,6 +57,8 @@ func TestCancelQueuedRunSucceeds(t *testing.T) {
 	createTenantRec := httptest.NewRecorder()
 	srv.Handler().ServeHTTP(createTenantRec, createTenantReq)
 
+	seedAgent(t, srv, "tnt_test", "agt_test")
+
 	// Create a run
 	createRunReq := httptest.NewRequest(http.MethodPost, "/v1/agents/agt_test/runs", bytes.NewBufferString(`{"input":{"type":"text","text":"hello"}}`))
 	createRunReq.Header.Set("Authorization", "Bearer test-token")
@@ -73,10 +106,7 @@ func TestCancelQueuedRunSucceeds(t *testing.T) {
 }
 
 func TestCancelCompletedRunReturns409(t *testing.T) {
-	srv, err := New("test")
-	if err != nil {
-		t.Fatalf("New server error: %v", err)
-	}
+	srv := newTestServer(t)
 
 	// Create tenant (requires tenants:admin scope)
 	createTenantReq := httptest.NewRequest(http.MethodPost, "/v1/admin/tenants", bytes.NewBufferString(`{"tenant_id":"tnt_test2"}`))
@@ -86,6 +116,8 @@ func TestCancelCompletedRunReturns409(t *testing.T) {
 	createTenantRec := httptest.NewRecorder()
 	srv.Handler().ServeHTTP(createTenantRec, createTenantReq)
 
+	seedAgent(t, srv, "tnt_test2", "agt_test")
+
 	// Create a run
 	createRunReq := httptest.NewRequest(http.MethodPost, "/v1/agents/agt_test/runs", bytes.NewBufferString(`{"input":{"type":"text","text":"hello"}}`))
 	createRunReq.Header.Set("Authorization", "Bearer test-token")
@@ -121,10 +153,7 @@ func TestCancelCompletedRunReturns409(t *testing.T) {
 }
 
 func TestCancelRunFromDifferentTenantReturns404(t *testing.T) {
-	srv, err := New("test")
-	if err != nil {
-		t.Fatalf("New server error: %v", err)
-	}
+	srv := newTestServer(t)
 
 	// Create tenant A (requires tenants:admin scope)
 	createTenantAReq := httptest.NewRequest(http.MethodPost, "/v1/admin/tenants", bytes.NewBufferString(`{"tenant_id":"tnt_alpha"}`))
@@ -142,6 +171,8 @@ func TestCancelRunFromDifferentTenantReturns404(t *testing.T) {
 	createTenantBRec := httptest.NewRecorder()
 	srv.Handler().ServeHTTP(createTenantBRec, createTenantBReq)
 
+	seedAgent(t, srv, "tnt_alpha", "agt_test")
+
 	// Create a run in tenant A
 	createRunReq := httptest.NewRequest(http.MethodPost, "/v1/agents/agt_test/runs", bytes.NewBufferString(`{"input":{"type":"text","text":"hello"}}`))
 	createRunReq.Header.Set("Authorization", "Bearer test-token")
@@ -168,10 +199,7 @@ func TestCancelRunFromDifferentTenantReturns404(t *testing.T) {
 }
 
 func TestCancelAlreadyCanceledRunReturns409(t *testing.T) {
-	srv, err := New("test")
-	if err != nil {
-		t.Fatalf("New server error: %v", err)
-	}
+	srv := newTestServer(t)
 
 	// Create tenant (requires tenants:admin scope)
 	createTenantReq := httptest.NewRequest(http.MethodPost, "/v1/admin/tenants", bytes.NewBufferString(`{"tenant_id":"tnt_test3"}`))
@@ -181,6 +209,8 @@ func TestCancelAlreadyCanceledRunReturns409(t *testing.T) {
 	createTenantRec := httptest.NewRecorder()
 	srv.Handler().ServeHTTP(createTenantRec, createTenantReq)
 
+	seedAgent(t, srv, "tnt_test3", "agt_test")
+
 	// Create a run
 	createRunReq := httptest.NewRequest(http.MethodPost, "/v1/agents/agt_test/runs", bytes.NewBufferString(`{"input":{"type":"text","text":"hello"}}`))
 	createRunReq.Header.Set("Authorization", "Bearer test-token")
@@ -218,10 +248,7 @@ func TestCancelAlreadyCanceledRunReturns409(t *testing.T) {
 }
 
 func TestListAgentsReturnsOnlyTenantAgents(t *testing.T) {
-	srv, err := New("test")
-	if err != nil {
-		t.Fatalf("New server error: %v", err)
-	}
+	srv := newTestServer(t)
 
 	// Create tenant A (requires tenants:admin scope)
 	createTenantAReq := httptest.NewRequest(http.MethodPost, "/v1/admin/tenants", bytes.NewBufferString(`{"tenant_id":"tnt_agent_a"}`))
@@ -315,10 +342,7 @@ func TestListAgentsReturnsOnlyTenantAgents(t *testing.T) {
 }
 
 func TestGetAgentFromDifferentTenantReturns404(t *testing.T) {
-	srv, err := New("test")
-	if err != nil {
-		t.Fatalf("New server error: %v", err)
-	}
+	srv := newTestServer(t)
 
 	// Create tenant A (requires tenants:admin scope)
 	createTenantAReq := httptest.NewRequest(http.MethodPost, "/v1/admin/tenants", bytes.NewBufferString(`{"tenant_id":"tnt_get_a"}`))
@@ -382,10 +406,7 @@ func TestGetAgentFromDifferentTenantReturns404(t *testing.T) {
 }
 
 func TestGetNonExistentAgentReturns404(t *testing.T) {
-	srv, err := New("test")
-	if err != nil {
-		t.Fatalf("New server error: %v", err)
-	}
+	srv := newTestServer(t)
 
 	// Create tenant (requires tenants:admin scope)
 	createTenantReq := httptest.NewRequest(http.MethodPost, "/v1/admin/tenants", bytes.NewBufferString(`{"tenant_id":"tnt_nonexist"}`))
@@ -406,3 +427,96 @@ func TestGetNonExistentAgentReturns404(t *testing.T) {
 		t.Fatalf("expected 404 for non-existent agent, got %d: %s", getRec.Code, getRec.Body.String())
 	}
 }
+
+func TestListRunsFiltersByAgentAndPaginates(t *testing.T) {
+	srv := newTestServer(t)
+	srv.runs, _ = storage.NewFileRunStore(filepath.Join(t.TempDir(), "runs.json"))
+
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_list", `{"tenant_id":"tnt_list"}`)
+	seedAgent(t, srv, "tnt_list", "agt_one")
+	seedAgent(t, srv, "tnt_list", "agt_two")
+	for _, agent := range []string{"agt_one", "agt_one", "agt_two"} {
+		rec := doRequest(t, srv, http.MethodPost, "/v1/agents/"+agent+"/runs", "tnt_list", `{"input":{"type":"text","text":"hi"}}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	list := func(path string) types.RunListResponse {
+		rec := doRequest(t, srv, http.MethodGet, path, "tnt_list", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+		var resp types.RunListResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal list response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := list("/v1/runs"); len(resp.Runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(resp.Runs))
+	}
+	if resp := list("/v1/agents/agt_one/runs?status=queued"); len(resp.Runs) != 2 {
+		t.Fatalf("expected 2 runs for agt_one, got %d", len(resp.Runs))
+	}
+	page := list("/v1/runs?limit=2")
+	if len(page.Runs) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected first page with cursor, got %d runs cursor=%q", len(page.Runs), page.NextCursor)
+	}
+	if rest := list("/v1/runs?limit=2&cursor=" + page.NextCursor); len(rest.Runs) != 1 || rest.NextCursor != "" {
+		t.Fatalf("expected last page with 1 run, got %d cursor=%q", len(rest.Runs), rest.NextCursor)
+	}
+	if resp := list("/v1/runs?status=completed"); len(resp.Runs) != 0 {
+		t.Fatalf("expected no completed runs, got %d", len(resp.Runs))
+	}
+
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/runs?created_after=yesterday", "tnt_list", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid created_after, got %d", rec.Code)
+	}
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_list_other", `{"tenant_id":"tnt_list_other"}`)
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/runs", "tnt_list_other", ""); !strings.Contains(rec.Body.String(), `"runs":[]`) {
+		t.Fatalf("expected empty list for other tenant, got %s", rec.Body.String())
+	}
+}
+
+func TestTenantQuotaOverridesApplyWithoutRestart(t *testing.T) {
+	release := make(chan struct{})
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	t.Cleanup(func() { close(release) })
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_quota", `{"tenant_id":"tnt_quota","quotas":{"runs":{"max_concurrent_runs":1}}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating tenant, got %d: %s", rec.Code, rec.Body.String())
+	}
+	seedAgent(t, srv, "tnt_quota", "agt_test")
+	create := func() int {
+		return doRequest(t, srv, http.MethodPost, "/v1/agents/agt_test/runs", "tnt_quota", `{"input":{"type":"text","text":"hi"}}`).Code
+	}
+	if code := create(); code != http.StatusCreated {
+		t.Fatalf("expected first run within quota, got %d", code)
+	}
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_test/runs", "tnt_quota", `{"input":{"type":"text","text":"hi"}}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected tenant quota of 1 concurrent run to apply, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" || rec.Header().Get("RateLimit-Limit") == "" {
+		t.Fatalf("expected Retry-After and rate limit headers on a quota denial, got %v", rec.Header())
+	}
+
+	rec = doRequest(t, srv, http.MethodPut, "/v1/admin/tenants/tnt_quota", "tnt_quota", `{"quotas":{"runs":{"max_concurrent_runs":2}}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 updating quotas, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if code := create(); code != http.StatusCreated {
+		t.Fatalf("expected raised quota to apply without restart, got %d", code)
+	}
+
+	rec = doRequest(t, srv, http.MethodPut, "/v1/admin/tenants/tnt_quota", "tnt_quota", `{"quotas":{"runs":{"max_concurrent_runs":-1}}}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid quota, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,166 @@
+package agentorchestrator
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/audit"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/auth"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/httpx"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,127}$`)
+
+// estimateTokens approximates a text's token count at about four characters per token,
+// which is close enough for capping history without a model-specific tokenizer.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// sessionHistory returns the prior turns of the run's session as model input messages.
+func (e *executor) sessionHistory(ctx context.Context, run types.Run) ([]map[string]any, error) {
+	session, ok, err := e.sessions.Get(ctx, run.TenantID, run.SessionID)
+	if err != nil || !ok {
+		return nil, err
+	}
+	history := make([]map[string]any, 0, len(session.Turns))
+	for _, turn := range session.Turns {
+		history = append(history, map[string]any{"role": turn.Role, "text": turn.Text})
+	}
+	return history, nil
+}
+
+// recordSessionTurns appends a completed run's input and output to its session, creating
+// the session on first use, then trims the oldest runs' turns until the session fits in
+// sessionMaxTokens. Failures are ignored so they never fail a run that already has its
+// output.
+func (e *executor) recordSessionTurns(ctx context.Context, run types.Run) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	session, ok, err := e.sessions.Get(ctx, run.TenantID, run.SessionID)
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	if !ok {
+		session = types.Session{TenantID: run.TenantID, SessionID: run.SessionID, CreatedAt: now}
+	}
+	if run.Input != nil && run.Input.Text != "" {
+		session.Turns = append(session.Turns, types.SessionTurn{
+			RunID: run.RunID, Role: "user", Text: run.Input.Text, Tokens: estimateTokens(run.Input.Text), CreatedAt: now,
+		})
+	}
+	if run.Output != nil && run.Output.Text != "" {
+		session.Turns = append(session.Turns, types.SessionTurn{
+			RunID: run.RunID, Role: "assistant", Text: run.Output.Text, Tokens: estimateTokens(run.Output.Text), CreatedAt: now,
+		})
+	}
+	trimSession(&session, e.sessionMaxTokens)
+	session.UpdatedAt = now
+	_ = e.sessions.Save(ctx, session)
+}
+
+// trimSession drops whole runs' turns, oldest first, until the session's token count is
+// within maxTokens, and refreshes its counters.
+func trimSession(session *types.Session, maxTokens int) {
+	total := 0
+	for _, turn := range session.Turns {
+		total += turn.Tokens
+	}
+	for total > maxTokens && len(session.Turns) > 0 {
+		oldest := session.Turns[0].RunID
+		for len(session.Turns) > 0 && session.Turns[0].RunID == oldest {
+			total -= session.Turns[0].Tokens
+			session.Turns = session.Turns[1:]
+		}
+	}
+	session.TurnCount = len(session.Turns)
+	session.TokenCount = total
+}
+
+// handleSessions serves GET /v1/sessions, GET /v1/sessions/{session_id} and
+// DELETE /v1/sessions/{session_id}.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	ac, _ := auth.Get(r.Context())
+	tenantID, ok := resolveTenant(w, r, ac)
+	if !ok {
+		return
+	}
+	if !s.tenantsExists(tenantID) {
+		httpx.Error(w, http.StatusForbidden, "tenant_unknown", "tenant not found", httpx.CorrelationID(r), false)
+		return
+	}
+
+	sessionID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sessions"), "/")
+	if sessionID == "" {
+		if r.Method != http.MethodGet {
+			httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+			return
+		}
+		s.handleSessionList(w, r, tenantID)
+		return
+	}
+	if strings.Contains(sessionID, "/") {
+		httpx.Error(w, http.StatusNotFound, "not_found", "not found", httpx.CorrelationID(r), false)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session, found, err := s.sessions.Get(r.Context(), tenantID, sessionID)
+		if err != nil {
+			httpx.Error(w, http.StatusInternalServerError, "session_lookup_failed", "failed to load session", httpx.CorrelationID(r), true)
+			return
+		}
+		if !found {
+			httpx.Error(w, http.StatusNotFound, "not_found", "session not found", httpx.CorrelationID(r), false)
+			return
+		}
+		httpx.JSON(w, http.StatusOK, types.SessionGetResponse{Session: session, CorrelationID: httpx.CorrelationID(r)})
+	case http.MethodDelete:
+		s.handleSessionDelete(w, r, tenantID, sessionID, ac)
+	default:
+		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+	}
+}
+
+// handleSessionList returns the tenant's sessions without their turns.
+func (s *Server) handleSessionList(w http.ResponseWriter, r *http.Request, tenantID string) {
+	sessions, err := s.sessions.List(r.Context(), tenantID)
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "session_list_failed", "failed to list sessions", httpx.CorrelationID(r), true)
+		return
+	}
+	if sessions == nil {
+		sessions = []types.Session{}
+	}
+	for i := range sessions {
+		sessions[i].Turns = nil
+	}
+	httpx.JSON(w, http.StatusOK, types.SessionListResponse{Sessions: sessions, CorrelationID: httpx.CorrelationID(r)})
+}
+
+func (s *Server) handleSessionDelete(w http.ResponseWriter, r *http.Request, tenantID, sessionID string, ac auth.AuthContext) {
+	s.exec.sessionMu.Lock()
+	deleted, err := s.sessions.Delete(r.Context(), tenantID, sessionID)
+	s.exec.sessionMu.Unlock()
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "session_delete_failed", "failed to delete session", httpx.CorrelationID(r), true)
+		return
+	}
+	if !deleted {
+		httpx.Error(w, http.StatusNotFound, "not_found", "session not found", httpx.CorrelationID(r), false)
+		return
+	}
+
+	s.audit.Log(audit.Entry{
+		TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "sessions.delete", Resource: "session/" + sessionID, Outcome: "allowed",
+		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package agentorchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestSessionHistoryCarriesAcrossRuns(t *testing.T) {
+	var mu sync.Mutex
+	var inputs []map[string]any
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ModelInvokeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		inputs = append(inputs, req.Input)
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "reply to " + req.Input["text"].(string)}})
+	})
+
+	first := createTestRun(t, srv, "tnt_ses", `{"input":{"type":"text","text":"one"},"context":{"session_id":"ses_chat"}}`)
+	// the run's event stream ends with its completed event, emitted after the session update
+	readEvents(t, srv, "tnt_ses", "/v1/runs/"+first.RunID+"/events")
+	second := createTestRun(t, srv, "tnt_ses", `{"input":{"type":"text","text":"two"},"context":{"session_id":"ses_chat"}}`)
+	if second.SessionID != "ses_chat" {
+		t.Fatalf("expected session_id on run, got %q", second.SessionID)
+	}
+	readEvents(t, srv, "tnt_ses", "/v1/runs/"+second.RunID+"/events")
+
+	mu.Lock()
+	if _, ok := inputs[0]["history"]; ok {
+		t.Fatalf("expected no history on the first turn, got %+v", inputs[0]["history"])
+	}
+	history, _ := inputs[1]["history"].([]any)
+	mu.Unlock()
+	if len(history) != 2 {
+		t.Fatalf("expected two prior turns, got %+v", history)
+	}
+	if turn, _ := history[1].(map[string]any); turn["role"] != "assistant" || turn["text"] != "reply to one" {
+		t.Fatalf("expected prior assistant turn, got %+v", history[1])
+	}
+
+	rec := doRequest(t, srv, http.MethodGet, "/v1/sessions", "tnt_ses", "")
+	var list types.SessionListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected session list, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(list.Sessions) != 1 || list.Sessions[0].TurnCount != 4 || list.Sessions[0].Turns != nil {
+		t.Fatalf("expected one session summary with 4 turns, got %+v", list.Sessions)
+	}
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/sessions/ses_chat", "tnt_ses_other", ""); rec.Code == http.StatusOK {
+		t.Fatalf("expected sessions to be tenant scoped")
+	}
+
+	if rec := doRequest(t, srv, http.MethodDelete, "/v1/sessions/ses_chat", "tnt_ses", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 deleting session, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/sessions/ses_chat", "tnt_ses", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestSessionHistoryIsCappedByTokens(t *testing.T) {
+	t.Setenv("AGENTOS_SESSION_MAX_TOKENS", "10")
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+
+	for _, text := range []string{"first message here", "second message here", "third"} {
+		run := createTestRun(t, srv, "tnt_ses_cap", `{"input":{"type":"text","text":"`+text+`"},"context":{"session_id":"ses_cap"}}`)
+		readEvents(t, srv, "tnt_ses_cap", "/v1/runs/"+run.RunID+"/events")
+	}
+
+	rec := doRequest(t, srv, http.MethodGet, "/v1/sessions/ses_cap", "tnt_ses_cap", "")
+	var resp types.SessionGetResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected session, got %d: %s", rec.Code, rec.Body.String())
+	}
+	session := resp.Session
+	if session.TokenCount > 10 {
+		t.Fatalf("expected token count within cap, got %d", session.TokenCount)
+	}
+	if len(session.Turns) == 0 || session.Turns[0].Text == "first message here" {
+		t.Fatalf("expected oldest turns trimmed, got %+v", session.Turns)
+	}
+	if last := session.Turns[len(session.Turns)-1]; last.Role != "assistant" {
+		t.Fatalf("expected newest turns kept, got %+v", session.Turns)
+	}
+
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_test/runs", "tnt_ses_cap", `{"input":{"type":"text","text":"x"},"context":{"session_id":"bad/id"}}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "session_id") {
+		t.Fatalf("expected 400 for invalid session_id, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,264 @@
+package agentorchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// maxToolResponseBytes bounds how much of a tool response is read.
+const maxToolResponseBytes = 1 << 20
+
+// protectedToolHeaders carry identity and authorization between AgentOS services; tool
+// configs may not set them, so a tool cannot impersonate a tenant or grant scopes.
+var protectedToolHeaders = []string{"Authorization", "X-Scopes", "X-Tenant-Id", "X-Principal-Id", "X-Subject-Type", "X-Api-Key-Id"}
+
+// toolRuntime executes tool calls for runs. Only the "http" kind is executable; other
+// kinds are reported back to the model as errors. HTTP calls are subject to the egress
+// policy: hosts in AGENTOS_TOOL_HTTP_ALLOWED_HOSTS only, never internal addresses.
+type toolRuntime struct {
+	client  *http.Client
+	egress  egressPolicy
+	timeout time.Duration
+}
+
+func newToolRuntimeFromEnv() *toolRuntime {
+	egress := egressPolicyFromEnv("AGENTOS_TOOL_HTTP_ALLOWED_HOSTS")
+	return &toolRuntime{
+		client:  egress.client(0),
+		egress:  egress,
+		timeout: time.Duration(envInt("AGENTOS_TOOL_TIMEOUT_MS", 10000)) * time.Millisecond,
+	}
+}
+
+// timeoutFor returns the tool's config.timeout_ms, or the runtime default.
+func (t *toolRuntime) timeoutFor(tool types.ToolDescriptor) time.Duration {
+	if ms, ok := tool.Config["timeout_ms"].(float64); ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return t.timeout
+}
+
+// Invoke validates args against the tool's input_schema and executes it.
+func (t *toolRuntime) Invoke(ctx context.Context, run types.Run, tool types.ToolDescriptor, args map[string]any) (map[string]any, *types.RunError) {
+	if err := validateToolArgs(tool.InputSchema, args); err != nil {
+		return nil, &types.RunError{Code: "tool_invalid_arguments", Message: err.Error(), Details: map[string]any{"tool": tool.Name}}
+	}
+	switch tool.Kind {
+	case "http":
+		return t.invokeHTTP(ctx, run, tool, args)
+	default:
+		return nil, &types.RunError{Code: "tool_kind_unsupported", Message: "tool kind " + tool.Kind + " is not executable", Details: map[string]any{"tool": tool.Name}}
+	}
+}
+
+// invokeHTTP sends the arguments as a JSON body to config.url (config.method, default POST)
+// and returns the JSON object response; non-object responses are wrapped as {"body": ...}.
+func (t *toolRuntime) invokeHTTP(ctx context.Context, run types.Run, tool types.ToolDescriptor, args map[string]any) (map[string]any, *types.RunError) {
+	details := map[string]any{"tool": tool.Name}
+	url, _ := tool.Config["url"].(string)
+	if strings.TrimSpace(url) == "" {
+		return nil, &types.RunError{Code: "tool_misconfigured", Message: "http tool requires config.url", Details: details}
+	}
+	method := http.MethodPost
+	if m, ok := tool.Config["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeoutFor(tool))
+	defer cancel()
+
+	b, err := json.Marshal(args)
+	if err != nil {
+		return nil, &types.RunError{Code: "tool_invalid_arguments", Message: err.Error(), Details: details}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, &types.RunError{Code: "tool_misconfigured", Message: err.Error(), Details: details}
+	}
+	if err := t.egress.checkURL(req.URL); err != nil {
+		return nil, &types.RunError{Code: "tool_egress_denied", Message: err.Error(), Details: details}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant-Id", run.TenantID)
+	req.Header.Set("X-Run-Id", run.RunID)
+	req.Header.Set("traceparent", traceContext(run.RunID).Traceparent)
+	if headers, ok := tool.Config["headers"].(map[string]any); ok {
+		for k, v := range headers {
+			if s, ok := v.(string); ok && !isProtectedToolHeader(k) {
+				req.Header.Set(k, s)
+			}
+		}
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &types.RunError{Code: "tool_timeout", Message: "tool call timed out", Details: details}
+		}
+		if errors.Is(err, errEgressDenied) {
+			return nil, &types.RunError{Code: "tool_egress_denied", Message: err.Error(), Details: details}
+		}
+		return nil, &types.RunError{Code: "tool_unavailable", Message: err.Error(), Details: details}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxToolResponseBytes))
+	if err != nil {
+		return nil, &types.RunError{Code: "tool_unavailable", Message: err.Error(), Details: details}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		details["http_status"] = resp.StatusCode
+		return nil, &types.RunError{Code: "tool_failed", Message: fmt.Sprintf("tool returned HTTP %d", resp.StatusCode), Details: details}
+	}
+
+	var out map[string]any
+	if err := json.Unmarshal(body, &out); err != nil || out == nil {
+		return map[string]any{"body": string(body)}, nil
+	}
+	return out, nil
+}
+
+func isProtectedToolHeader(name string) bool {
+	for _, h := range protectedToolHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCallsFromModel extracts tool calls from a model output. Both the flat form
+// {"id","name","arguments"} and the OpenAI form {"id","function":{"name","arguments"}}
+// are accepted; arguments may be an object or a JSON-encoded string.
+func toolCallsFromModel(output map[string]any) []types.ToolCall {
+	raw, _ := output["tool_calls"].([]any)
+	calls := make([]types.ToolCall, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		call := types.ToolCall{}
+		call.ID, _ = m["id"].(string)
+		call.Name, _ = m["name"].(string)
+		argsRaw := m["arguments"]
+		if fn, ok := m["function"].(map[string]any); ok {
+			if name, ok := fn["name"].(string); ok {
+				call.Name = name
+			}
+			argsRaw = fn["arguments"]
+		}
+		switch a := argsRaw.(type) {
+		case map[string]any:
+			call.Arguments = a
+		case string:
+			_ = json.Unmarshal([]byte(a), &call.Arguments)
+		}
+		if call.Arguments == nil {
+			call.Arguments = map[string]any{}
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// validateToolArgs checks args against the JSON Schema subset used by tool descriptors:
+// type, properties, required, enum, items and additionalProperties: false.
+func validateToolArgs(schema map[string]any, args map[string]any) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	return validateSchemaValue("arguments", schema, args)
+}
+
+func validateSchemaValue(path string, schema map[string]any, value any) error {
+	if typ, ok := schema["type"].(string); ok && !schemaTypeMatches(typ, value) {
+		return fmt.Errorf("%s must be of type %s", path, typ)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, present := v[name]; name != "" && !present {
+					return fmt.Errorf("%s.%s is required", path, name)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			propSchema, ok := props[k].(map[string]any)
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s.%s is not allowed", path, k)
+				}
+				continue
+			}
+			if err := validateSchemaValue(path+"."+k, propSchema, v[k]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchemaValue(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func schemaTypeMatches(typ string, value any) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
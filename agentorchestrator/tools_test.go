@@ -0,0 +1,222 @@
+package agentorchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestValidateToolArgs(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"required":             []any{"query"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer"},
+			"mode":  map[string]any{"type": "string", "enum": []any{"fast", "deep"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+	cases := []struct {
+		name string
+		args map[string]any
+		ok   bool
+	}{
+		{"valid", map[string]any{"query": "go", "limit": float64(3), "mode": "fast", "tags": []any{"a"}}, true},
+		{"missing required", map[string]any{"limit": float64(3)}, false},
+		{"wrong type", map[string]any{"query": float64(1)}, false},
+		{"non-integer", map[string]any{"query": "go", "limit": 1.5}, false},
+		{"enum", map[string]any{"query": "go", "mode": "slow"}, false},
+		{"array items", map[string]any{"query": "go", "tags": []any{float64(1)}}, false},
+		{"additional property", map[string]any{"query": "go", "extra": true}, false},
+	}
+	for _, tc := range cases {
+		err := validateToolArgs(schema, tc.args)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%s: expected ok=%v, got err=%v", tc.name, tc.ok, err)
+		}
+	}
+	if err := validateToolArgs(nil, map[string]any{"anything": 1}); err != nil {
+		t.Fatalf("expected empty schema to accept any arguments, got %v", err)
+	}
+}
+
+func TestExecutorRunsHTTPToolCalls(t *testing.T) {
+	var toolMu sync.Mutex
+	var toolArgs []map[string]any
+	toolSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Tenant-Id") != "tnt_tools" || r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("expected tenant and configured headers, got %v", r.Header)
+		}
+		var args map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&args)
+		toolMu.Lock()
+		toolArgs = append(toolArgs, args)
+		toolMu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": []string{"doc-1"}})
+	}))
+	t.Cleanup(toolSrv.Close)
+
+	var modelMu sync.Mutex
+	var modelInputs []map[string]any
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ModelInvokeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		modelMu.Lock()
+		modelInputs = append(modelInputs, req.Input)
+		modelMu.Unlock()
+		if _, ok := req.Input["tool_results"]; ok {
+			_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "found doc-1"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{
+			"tool_calls": []any{
+				map[string]any{"id": "call_1", "name": "search", "arguments": map[string]any{"query": "agents"}},
+				map[string]any{"id": "call_2", "function": map[string]any{"name": "search", "arguments": `{"limit":1}`}},
+			},
+		}})
+	})
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_tools", `{"tenant_id":"tnt_tools"}`)
+	rec := doRequest(t, srv, http.MethodPost, "/v1/agents", "tnt_tools", `{
+		"agent_id": "agt_tools", "name": "Tools", "status": "active",
+		"definition": {"tools": [{
+			"name": "search", "kind": "http",
+			"config": {"url": "`+toolSrv.URL+`", "headers": {"X-Api-Key": "secret"}, "timeout_ms": 2000},
+			"input_schema": {"type": "object", "required": ["query"], "properties": {"query": {"type": "string"}}}
+		}]}
+	}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, srv, http.MethodPost, "/v1/agents/agt_tools/runs", "tnt_tools", `{"input":{"type":"text","text":"find"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp types.RunCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal create response: %v", err)
+	}
+
+	done := waitForStatus(t, srv, "tnt_tools", resp.Run.RunID, "completed", "failed")
+	if done.Status != "completed" || done.Output == nil || done.Output.Text != "found doc-1" {
+		t.Fatalf("expected completed run with final model output, got %s %+v (error=%+v)", done.Status, done.Output, done.Error)
+	}
+	toolMu.Lock()
+	if len(toolArgs) != 1 || toolArgs[0]["query"] != "agents" {
+		t.Fatalf("expected only the valid call to reach the tool, got %+v", toolArgs)
+	}
+	toolMu.Unlock()
+
+	modelMu.Lock()
+	defer modelMu.Unlock()
+	if len(modelInputs) != 2 {
+		t.Fatalf("expected two model steps, got %d", len(modelInputs))
+	}
+	results, _ := modelInputs[1]["tool_results"].([]any)
+	if len(results) != 2 {
+		t.Fatalf("expected both tool results fed back to the model, got %+v", modelInputs[1]["tool_results"])
+	}
+	first, _ := results[0].(map[string]any)
+	second, _ := results[1].(map[string]any)
+	if first["tool_call_id"] != "call_1" || first["output"] == nil {
+		t.Fatalf("expected tool output for call_1, got %+v", first)
+	}
+	if errObj, _ := second["error"].(map[string]any); errObj["code"] != "tool_invalid_arguments" {
+		t.Fatalf("expected schema violation for call_2, got %+v", second)
+	}
+
+	events := readEvents(t, srv, "tnt_tools", "/v1/runs/"+done.RunID+"/events")
+	var started, completed []types.Event
+	for _, ev := range events {
+		switch ev.Type {
+		case "agentos.tool.call.started":
+			started = append(started, ev)
+		case "agentos.tool.call.completed":
+			completed = append(completed, ev)
+		}
+	}
+	if len(started) != 2 || len(completed) != 2 {
+		t.Fatalf("expected two tool call started/completed events, got %d/%d", len(started), len(completed))
+	}
+	if completed[0].Payload["status"] != "ok" || completed[1].Payload["status"] != "error" {
+		t.Fatalf("expected ok then error tool statuses, got %+v %+v", completed[0].Payload, completed[1].Payload)
+	}
+	if completed[0].StepID == "" || completed[0].StepID != started[0].StepID {
+		t.Fatalf("expected tool events to share a step id, got %q %q", started[0].StepID, completed[0].StepID)
+	}
+}
+
+func TestHTTPToolTimesOut(t *testing.T) {
+	block := make(chan struct{})
+	toolSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	t.Cleanup(toolSrv.Close)
+	t.Cleanup(func() { close(block) })
+
+	t.Setenv("AGENTOS_EGRESS_ALLOW_PRIVATE", "1")
+	rt := newToolRuntimeFromEnv()
+	tool := types.ToolDescriptor{Name: "slow", Kind: "http", Config: map[string]any{"url": toolSrv.URL, "timeout_ms": float64(50)}}
+	_, runErr := rt.Invoke(context.Background(), types.Run{TenantID: "tnt_tools", RunID: "run_1"}, tool, map[string]any{})
+	if runErr == nil || runErr.Code != "tool_timeout" {
+		t.Fatalf("expected tool_timeout, got %+v", runErr)
+	}
+
+	tool = types.ToolDescriptor{Name: "fn", Kind: "function"}
+	if _, runErr := rt.Invoke(context.Background(), types.Run{}, tool, map[string]any{}); runErr == nil || runErr.Code != "tool_kind_unsupported" {
+		t.Fatalf("expected tool_kind_unsupported, got %+v", runErr)
+	}
+}
+
+func TestHTTPToolEgressPolicy(t *testing.T) {
+	var seen http.Header
+	toolSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, strings.Replace("http://"+r.Host+"/ok", "127.0.0.1", "localhost", 1), http.StatusFound)
+			return
+		}
+		seen = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(toolSrv.Close)
+	run := types.Run{TenantID: "tnt_tools", RunID: "run_1"}
+	invoke := func(rt *toolRuntime, url string, headers map[string]any) *types.RunError {
+		t.Helper()
+		tool := types.ToolDescriptor{Name: "fetch", Kind: "http", Config: map[string]any{"url": url, "headers": headers}}
+		_, runErr := rt.Invoke(context.Background(), run, tool, map[string]any{})
+		return runErr
+	}
+
+	t.Setenv("AGENTOS_EGRESS_ALLOW_PRIVATE", "")
+	if runErr := invoke(newToolRuntimeFromEnv(), toolSrv.URL+"/ok", nil); runErr == nil || runErr.Code != "tool_egress_denied" {
+		t.Fatalf("expected loopback to be denied, got %+v", runErr)
+	}
+
+	t.Setenv("AGENTOS_EGRESS_ALLOW_PRIVATE", "1")
+	t.Setenv("AGENTOS_TOOL_HTTP_ALLOWED_HOSTS", "127.0.0.1, .example.com")
+	rt := newToolRuntimeFromEnv()
+	if runErr := invoke(rt, "http://169.254.169.254/latest/meta-data", nil); runErr == nil || runErr.Code != "tool_egress_denied" {
+		t.Fatalf("expected link-local to be denied even when private addresses are allowed, got %+v", runErr)
+	}
+	if runErr := invoke(rt, "http://internal.corp/", nil); runErr == nil || runErr.Code != "tool_egress_denied" {
+		t.Fatalf("expected a host outside the allowlist to be denied, got %+v", runErr)
+	}
+	if runErr := invoke(rt, toolSrv.URL+"/redirect", nil); runErr == nil || runErr.Code != "tool_egress_denied" {
+		t.Fatalf("expected a redirect outside the allowlist to be denied, got %+v", runErr)
+	}
+
+	headers := map[string]any{"Authorization": "Bearer x", "X-Scopes": "tenants:admin", "X-Tenant-Id": "tnt_other", "X-Api-Key": "k"}
+	if runErr := invoke(rt, toolSrv.URL+"/ok", headers); runErr != nil {
+		t.Fatalf("expected the allowed call to succeed, got %+v", runErr)
+	}
+	if seen.Get("Authorization") != "" || seen.Get("X-Scopes") != "" || seen.Get("X-Tenant-Id") != "tnt_tools" || seen.Get("X-Api-Key") != "k" {
+		t.Fatalf("expected protected headers stripped and others kept, got %v", seen)
+	}
+}
@@ -0,0 +1,453 @@
+package agentorchestrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/audit"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/auth"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/httpx"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/id"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// webhookEventTypes are the run lifecycle events delivered to webhooks.
+var webhookEventTypes = []string{
+	"agentos.run.created", "agentos.run.started", "agentos.run.completed",
+	"agentos.run.failed", "agentos.run.canceled", "agentos.run.timed_out",
+}
+
+const (
+	webhookWorkers   = 2
+	webhookQueueSize = 1024
+
+	defaultDeliveryListLimit = 50
+	maxDeliveryListLimit     = 200
+)
+
+// webhookJob is one pending delivery attempt; body is the signed payload.
+type webhookJob struct {
+	delivery types.WebhookDelivery
+	body     []byte
+}
+
+// webhookDispatcher delivers run lifecycle events to the tenant's webhooks. Each event is
+// POSTed as an EventEnvelope signed with the webhook's secret; failed attempts are retried
+// with exponential backoff and every attempt is recorded in the delivery log. Deliveries
+// still pending when the process stops are resumed on the next start, and finished ones
+// are pruned from the log after the retention period.
+type webhookDispatcher struct {
+	store       storage.WebhookStore
+	events      storage.EventStore
+	egress      egressPolicy
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	retention   time.Duration
+	queue       chan webhookJob
+}
+
+func newWebhookDispatcherFromEnv(store storage.WebhookStore, events storage.EventStore) *webhookDispatcher {
+	egress := egressPolicyFromEnv("AGENTOS_WEBHOOK_ALLOWED_HOSTS")
+	return &webhookDispatcher{
+		store:       store,
+		events:      events,
+		egress:      egress,
+		client:      egress.client(time.Duration(envInt("AGENTOS_WEBHOOK_TIMEOUT_MS", 5000)) * time.Millisecond),
+		maxAttempts: envInt("AGENTOS_WEBHOOK_MAX_ATTEMPTS", 5),
+		backoff:     time.Duration(envInt("AGENTOS_WEBHOOK_BACKOFF_MS", 1000)) * time.Millisecond,
+		retention:   time.Duration(envInt("AGENTOS_WEBHOOK_RETENTION_HOURS", 168)) * time.Hour,
+		queue:       make(chan webhookJob, webhookQueueSize),
+	}
+}
+
+// Start launches the delivery workers, resumes the deliveries left pending by a previous
+// process and prunes the delivery log hourly; all of it stops when ctx is canceled.
+func (d *webhookDispatcher) Start(ctx context.Context) {
+	// listed before events of this process are dispatched, so none is resumed twice
+	pending, _ := d.store.ListPendingDeliveries(ctx)
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-d.queue:
+					d.attempt(ctx, job)
+				}
+			}
+		}()
+	}
+	go d.resume(ctx, pending)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			_, _ = d.store.PruneDeliveries(ctx, time.Now().Add(-d.retention).UTC().Format(time.RFC3339))
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// resume queues pending deliveries again, each at its next_attempt_at if still ahead. The
+// payload is rebuilt from the run's event log.
+func (d *webhookDispatcher) resume(ctx context.Context, pending []types.WebhookDelivery) {
+	for _, delivery := range pending {
+		body, err := d.eventBody(ctx, delivery)
+		if err != nil {
+			delivery.Status = "failed"
+			delivery.Error = "event not found"
+			delivery.NextAttemptAt = ""
+			delivery.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+			_ = d.store.SaveDelivery(context.Background(), delivery)
+			continue
+		}
+		job := webhookJob{delivery: delivery, body: body}
+		if next, err := time.Parse(time.RFC3339, delivery.NextAttemptAt); err == nil && time.Until(next) > 0 {
+			time.AfterFunc(time.Until(next), func() { d.enqueue(job) })
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case d.queue <- job:
+		}
+	}
+}
+
+// eventBody returns the payload of delivery's event.
+func (d *webhookDispatcher) eventBody(ctx context.Context, delivery types.WebhookDelivery) ([]byte, error) {
+	events, err := d.events.List(ctx, delivery.TenantID, delivery.RunID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if event.EventID == delivery.EventID {
+			return json.Marshal(types.EventEnvelope{Event: event})
+		}
+	}
+	return nil, errors.New("event not found")
+}
+
+// Dispatch records a pending delivery of event for every tenant webhook subscribed to its
+// type and queues the first attempts. It never blocks on delivery.
+func (d *webhookDispatcher) Dispatch(event types.Event) {
+	if !containsString(webhookEventTypes, event.Type) {
+		return
+	}
+	ctx := context.Background()
+	webhooks, err := d.store.List(ctx, event.TenantID)
+	if err != nil || len(webhooks) == 0 {
+		return
+	}
+	body, err := json.Marshal(types.EventEnvelope{Event: event})
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, webhook := range webhooks {
+		if len(webhook.EventTypes) > 0 && !containsString(webhook.EventTypes, event.Type) {
+			continue
+		}
+		delivery := types.WebhookDelivery{
+			TenantID:   event.TenantID,
+			DeliveryID: id.New("dlv"),
+			WebhookID:  webhook.WebhookID,
+			URL:        webhook.URL,
+			EventID:    event.EventID,
+			EventType:  event.Type,
+			RunID:      event.RunID,
+			Status:     "pending",
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+			continue
+		}
+		d.enqueue(webhookJob{delivery: delivery, body: body})
+	}
+}
+
+func (d *webhookDispatcher) enqueue(job webhookJob) {
+	select {
+	case d.queue <- job:
+	default:
+		job.delivery.Status = "failed"
+		job.delivery.Error = "delivery queue full"
+		job.delivery.NextAttemptAt = ""
+		job.delivery.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		_ = d.store.SaveDelivery(context.Background(), job.delivery)
+	}
+}
+
+// attempt makes one delivery attempt and records its outcome, scheduling the next attempt
+// when the failure is retryable and attempts remain.
+func (d *webhookDispatcher) attempt(ctx context.Context, job webhookJob) {
+	delivery := job.delivery
+	delivery.Attempts++
+	delivery.NextAttemptAt = ""
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+
+	retryable := false
+	webhook, ok, err := d.store.Get(ctx, delivery.TenantID, delivery.WebhookID)
+	switch {
+	case err != nil:
+		delivery.Error = "failed to load webhook"
+		retryable = true
+	case !ok:
+		delivery.Error = "webhook deleted"
+	default:
+		delivery.ResponseStatus, err = d.post(ctx, webhook, delivery, job.body)
+		if err != nil {
+			delivery.Error = err.Error()
+			retryable = true
+		} else if delivery.ResponseStatus < 200 || delivery.ResponseStatus >= 300 {
+			delivery.Error = "endpoint returned HTTP " + strconv.Itoa(delivery.ResponseStatus)
+			retryable = delivery.ResponseStatus == http.StatusRequestTimeout || delivery.ResponseStatus == http.StatusTooManyRequests || delivery.ResponseStatus >= 500
+		}
+	}
+
+	now := time.Now().UTC()
+	delivery.UpdatedAt = now.Format(time.RFC3339)
+	switch {
+	case delivery.Error == "":
+		delivery.Status = "succeeded"
+	case retryable && delivery.Attempts < d.maxAttempts:
+		delay := d.backoff << min(delivery.Attempts-1, 10)
+		delivery.NextAttemptAt = now.Add(delay).Format(time.RFC3339)
+		next := webhookJob{delivery: delivery, body: job.body}
+		time.AfterFunc(delay, func() { d.enqueue(next) })
+	default:
+		delivery.Status = "failed"
+	}
+	_ = d.store.SaveDelivery(context.Background(), delivery)
+}
+
+func (d *webhookDispatcher) post(ctx context.Context, webhook types.Webhook, delivery types.WebhookDelivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "agentos-webhooks")
+	req.Header.Set("X-AgentOS-Event", delivery.EventType)
+	req.Header.Set("X-AgentOS-Delivery", delivery.DeliveryID)
+	req.Header.Set("X-AgentOS-Webhook-Id", delivery.WebhookID)
+	req.Header.Set("X-AgentOS-Signature", "t="+strconv.FormatInt(timestamp, 10)+",v1="+signWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if errors.Is(err, errEgressDenied) {
+		return 0, errors.New("endpoint address not allowed")
+	}
+	if err != nil {
+		return 0, errors.New("endpoint unreachable")
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the hex HMAC-SHA256 of "{timestamp}.{body}" keyed with secret.
+// Receivers recompute it from the t= value of X-AgentOS-Signature and the raw body, and
+// should reject stale timestamps to prevent replays.
+func signWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// handleWebhooks serves GET and POST /v1/webhooks and GET and DELETE
+// /v1/webhooks/{webhook_id}.
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	ac, _ := auth.Get(r.Context())
+	tenantID, ok := resolveTenant(w, r, ac)
+	if !ok {
+		return
+	}
+	if !s.tenantsExists(tenantID) {
+		httpx.Error(w, http.StatusForbidden, "tenant_unknown", "tenant not found", httpx.CorrelationID(r), false)
+		return
+	}
+
+	webhookID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/webhooks"), "/")
+	if webhookID == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.handleWebhookList(w, r, tenantID)
+		case http.MethodPost:
+			s.handleWebhookCreate(w, r, tenantID, ac)
+		default:
+			httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+		}
+		return
+	}
+	if strings.Contains(webhookID, "/") {
+		httpx.Error(w, http.StatusNotFound, "not_found", "not found", httpx.CorrelationID(r), false)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		webhook, found, err := s.webhooks.Get(r.Context(), tenantID, webhookID)
+		if err != nil {
+			httpx.Error(w, http.StatusInternalServerError, "webhook_lookup_failed", "failed to load webhook", httpx.CorrelationID(r), true)
+			return
+		}
+		if !found {
+			httpx.Error(w, http.StatusNotFound, "not_found", "webhook not found", httpx.CorrelationID(r), false)
+			return
+		}
+		webhook.Secret = ""
+		httpx.JSON(w, http.StatusOK, types.WebhookResponse{Webhook: webhook, CorrelationID: httpx.CorrelationID(r)})
+	case http.MethodDelete:
+		deleted, err := s.webhooks.Delete(r.Context(), tenantID, webhookID)
+		if err != nil {
+			httpx.Error(w, http.StatusInternalServerError, "webhook_delete_failed", "failed to delete webhook", httpx.CorrelationID(r), true)
+			return
+		}
+		if !deleted {
+			httpx.Error(w, http.StatusNotFound, "not_found", "webhook not found", httpx.CorrelationID(r), false)
+			return
+		}
+		s.audit.Log(audit.Entry{
+			TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "webhooks.delete", Resource: "webhook/" + webhookID, Outcome: "allowed",
+			CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+	}
+}
+
+// handleWebhookList returns the tenant's webhooks without their secrets.
+func (s *Server) handleWebhookList(w http.ResponseWriter, r *http.Request, tenantID string) {
+	webhooks, err := s.webhooks.List(r.Context(), tenantID)
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "webhook_list_failed", "failed to list webhooks", httpx.CorrelationID(r), true)
+		return
+	}
+	if webhooks == nil {
+		webhooks = []types.Webhook{}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	httpx.JSON(w, http.StatusOK, types.WebhookListResponse{Webhooks: webhooks, CorrelationID: httpx.CorrelationID(r)})
+}
+
+// handleWebhookCreate serves POST /v1/webhooks. The response is the only one that carries
+// the webhook's secret.
+func (s *Server) handleWebhookCreate(w http.ResponseWriter, r *http.Request, tenantID string, ac auth.AuthContext) {
+	var req types.WebhookCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
+		return
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", "url must be an absolute http or https URL", httpx.CorrelationID(r), false)
+		return
+	}
+	if err := s.exec.webhooks.egress.checkURL(u); err != nil {
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", "url is not an allowed webhook destination: "+err.Error(), httpx.CorrelationID(r), false)
+		return
+	}
+	for _, eventType := range req.EventTypes {
+		if !containsString(webhookEventTypes, eventType) {
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", "event_types must be among "+strings.Join(webhookEventTypes, ", "), httpx.CorrelationID(r), false)
+			return
+		}
+	}
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 24)
+		_, _ = rand.Read(b)
+		secret = "whsec_" + hex.EncodeToString(b)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	webhook := types.Webhook{
+		TenantID:   tenantID,
+		WebhookID:  id.New("whk"),
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.webhooks.Save(r.Context(), webhook); err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "webhook_persist_failed", "failed to persist webhook", httpx.CorrelationID(r), true)
+		return
+	}
+
+	s.audit.Log(audit.Entry{
+		TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "webhooks.create", Resource: "webhook/" + webhook.WebhookID, Outcome: "allowed",
+		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+		Meta: map[string]any{"url": webhook.URL, "event_types": webhook.EventTypes},
+	})
+	httpx.JSON(w, http.StatusCreated, types.WebhookResponse{Webhook: webhook, CorrelationID: httpx.CorrelationID(r)})
+}
+
+// handleWebhookDeliveries serves GET /v1/admin/tenants/{tenant_id}/webhooks/deliveries, the
+// delivery log filtered by the webhook_id and status query parameters.
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request, tenantID string) {
+	if r.Method != http.MethodGet {
+		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
+		return
+	}
+	if !s.tenantsExists(tenantID) {
+		httpx.Error(w, http.StatusNotFound, "not_found", "tenant not found", httpx.CorrelationID(r), false)
+		return
+	}
+	q := r.URL.Query()
+	filter := storage.DeliveryFilter{
+		WebhookID: q.Get("webhook_id"),
+		Status:    q.Get("status"),
+		Limit:     defaultDeliveryListLimit,
+	}
+	switch filter.Status {
+	case "", "pending", "succeeded", "failed":
+	default:
+		httpx.Error(w, http.StatusBadRequest, "invalid_request", "status must be one of pending, succeeded, failed", httpx.CorrelationID(r), false)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			httpx.Error(w, http.StatusBadRequest, "invalid_request", "limit must be a positive integer", httpx.CorrelationID(r), false)
+			return
+		}
+		if limit > maxDeliveryListLimit {
+			limit = maxDeliveryListLimit
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := s.webhooks.ListDeliveries(r.Context(), tenantID, filter)
+	if err != nil {
+		httpx.Error(w, http.StatusInternalServerError, "delivery_list_failed", "failed to list webhook deliveries", httpx.CorrelationID(r), true)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []types.WebhookDelivery{}
+	}
+	httpx.JSON(w, http.StatusOK, types.WebhookDeliveryListResponse{Deliveries: deliveries, CorrelationID: httpx.CorrelationID(r)})
+}
@@ -0,0 +1,220 @@
+package agentorchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestWebhooksDeliverSignedRunEvents(t *testing.T) {
+	t.Setenv("AGENTOS_WEBHOOK_BACKOFF_MS", "10")
+	type received struct {
+		eventType string
+		signature string
+		body      []byte
+	}
+	var mu sync.Mutex
+	var got []received
+	failed := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if !failed {
+			// the first delivery fails once and is retried
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		got = append(got, received{eventType: r.Header.Get("X-AgentOS-Event"), signature: r.Header.Get("X-AgentOS-Signature"), body: body})
+	}))
+	t.Cleanup(receiver.Close)
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.ModelInvokeResponse{Output: map[string]any{"text": "ok"}})
+	})
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_hooks", `{"tenant_id":"tnt_hooks"}`)
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/webhooks", "tnt_hooks", `{"url":"`+receiver.URL+`","event_types":["agentos.run.created","agentos.run.completed"],"secret":"s3cret"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating webhook, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created types.WebhookResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &created)
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/webhooks/"+created.Webhook.WebhookID, "tnt_hooks", ""); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "s3cret") {
+		t.Fatalf("expected webhook without its secret, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	run := createTestRun(t, srv, "tnt_hooks", `{"input":{"type":"text","text":"hello"}}`)
+	waitForStatus(t, srv, "tnt_hooks", run.RunID, "completed", "failed")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected two deliveries, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	deliveries := append([]received(nil), got...)
+	mu.Unlock()
+	eventTypes := map[string]bool{}
+	for _, d := range deliveries {
+		eventTypes[d.eventType] = true
+		var envelope types.EventEnvelope
+		if err := json.Unmarshal(d.body, &envelope); err != nil || envelope.Event.RunID != run.RunID || envelope.Event.Type != d.eventType {
+			t.Fatalf("expected event envelope for the run, got %s", d.body)
+		}
+		ts, sig, _ := strings.Cut(strings.TrimPrefix(d.signature, "t="), ",v1=")
+		timestamp, _ := strconv.ParseInt(ts, 10, 64)
+		if sig != signWebhookPayload("s3cret", timestamp, d.body) {
+			t.Fatalf("expected valid signature, got %q", d.signature)
+		}
+	}
+	if !eventTypes["agentos.run.created"] || !eventTypes["agentos.run.completed"] || eventTypes["agentos.run.started"] {
+		t.Fatalf("expected only subscribed event types, got %v", eventTypes)
+	}
+
+	var log types.WebhookDeliveryListResponse
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		rec = doRequest(t, srv, http.MethodGet, "/v1/admin/tenants/tnt_hooks/webhooks/deliveries?webhook_id="+created.Webhook.WebhookID, "tnt_hooks", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected delivery log, got %d: %s", rec.Code, rec.Body.String())
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &log)
+		if len(log.Deliveries) == 2 && log.Deliveries[0].Status == "succeeded" && log.Deliveries[1].Status == "succeeded" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected two succeeded deliveries, got %+v", log.Deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	attempts := log.Deliveries[0].Attempts + log.Deliveries[1].Attempts
+	if attempts != 3 {
+		t.Fatalf("expected one retried delivery (3 attempts total), got %d", attempts)
+	}
+
+	// limits above the maximum are clamped; non-positive ones are rejected
+	if rec = doRequest(t, srv, http.MethodGet, "/v1/admin/tenants/tnt_hooks/webhooks/deliveries?limit=500", "tnt_hooks", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected an oversized limit to be clamped, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec = doRequest(t, srv, http.MethodGet, "/v1/admin/tenants/tnt_hooks/webhooks/deliveries?limit=0", "tnt_hooks", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a zero limit to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestWebhookCreateValidatesRequest(t *testing.T) {
+	srv := newExecutingServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_hooks_bad", `{"tenant_id":"tnt_hooks_bad"}`)
+
+	for _, body := range []string{
+		`{"url":"ftp://example.com/hook"}`,
+		`{"url":"/relative"}`,
+		`{"url":"https://example.com/hook","event_types":["agentos.run.step.started"]}`,
+		`{"url":"http://169.254.169.254/latest/meta-data"}`,
+	} {
+		if rec := doRequest(t, srv, http.MethodPost, "/v1/webhooks", "tnt_hooks_bad", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	rec := doRequest(t, srv, http.MethodPost, "/v1/webhooks", "tnt_hooks_bad", `{"url":"https://example.com/hook"}`)
+	var created types.WebhookResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated || !strings.HasPrefix(created.Webhook.Secret, "whsec_") {
+		t.Fatalf("expected generated secret on create, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, srv, http.MethodDelete, "/v1/webhooks/"+created.Webhook.WebhookID, "tnt_hooks_bad", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 deleting webhook, got %d", rec.Code)
+	}
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/webhooks/"+created.Webhook.WebhookID, "tnt_hooks_bad", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestWebhookCreateRejectsPrivateAddresses(t *testing.T) {
+	srv := newTestServer(t)
+	t.Setenv("AGENTOS_EGRESS_ALLOW_PRIVATE", "")
+	t.Setenv("AGENTOS_WEBHOOK_ALLOWED_HOSTS", "hooks.example.com")
+	srv.exec.webhooks = newWebhookDispatcherFromEnv(srv.webhooks, srv.events)
+	doRequest(t, srv, http.MethodPost, "/v1/admin/tenants", "tnt_hooks_ssrf", `{"tenant_id":"tnt_hooks_ssrf"}`)
+
+	for _, body := range []string{
+		`{"url":"http://127.0.0.1:8081/v1/admin/tenants"}`,
+		`{"url":"http://10.0.0.5/hook"}`,
+		`{"url":"https://other.example.com/hook"}`,
+	} {
+		if rec := doRequest(t, srv, http.MethodPost, "/v1/webhooks", "tnt_hooks_ssrf", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/webhooks", "tnt_hooks_ssrf", `{"url":"https://hooks.example.com/agentos"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected an allowed host to register, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWebhookDeliveriesPendingAtShutdownResume(t *testing.T) {
+	delivered := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var envelope types.EventEnvelope
+		_ = json.NewDecoder(r.Body).Decode(&envelope)
+		delivered <- envelope.Event.EventID
+	}))
+	t.Cleanup(receiver.Close)
+	srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// left behind by a previous process: the event and its pending delivery
+	event, err := srv.events.Append(ctx, types.Event{TenantID: "tnt_hooks_resume", RunID: "run_1", Type: "agentos.run.completed"})
+	if err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+	if err := srv.webhooks.Save(ctx, types.Webhook{TenantID: "tnt_hooks_resume", WebhookID: "whk_1", URL: receiver.URL, Secret: "s"}); err != nil {
+		t.Fatalf("save webhook: %v", err)
+	}
+	delivery := types.WebhookDelivery{
+		TenantID: "tnt_hooks_resume", DeliveryID: "dlv_1", WebhookID: "whk_1", URL: receiver.URL,
+		EventID: event.EventID, EventType: event.Type, RunID: "run_1", Status: "pending", Attempts: 1,
+		NextAttemptAt: time.Now().UTC().Format(time.RFC3339), CreatedAt: event.Time,
+	}
+	if err := srv.webhooks.SaveDelivery(ctx, delivery); err != nil {
+		t.Fatalf("save delivery: %v", err)
+	}
+
+	srv.Start(ctx)
+	select {
+	case got := <-delivered:
+		if got != event.EventID {
+			t.Fatalf("expected the pending event delivered, got %s", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the pending delivery resumed")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, _ := srv.webhooks.ListDeliveries(ctx, "tnt_hooks_resume", storage.DeliveryFilter{})
+		if len(deliveries) == 1 && deliveries[0].Status == "succeeded" && deliveries[0].Attempts == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the resumed delivery recorded as succeeded, got %+v", deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
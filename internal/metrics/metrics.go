@@ -39,6 +39,14 @@ var (
 		[]string{"service", "kind"},
 	)
 
+	quotaBackendErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "agentos_quota_backend_errors_total",
+			Help: "Shared quota backend (Redis) operations that failed.",
+		},
+		[]string{"scope", "op"},
+	)
+
 	fedForwardFailures = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "agentos_federation_forward_failures_total",
@@ -54,6 +62,7 @@ func init() {
 	_ = registry.Register(httpRequests)
 	_ = registry.Register(httpDuration)
 	_ = registry.Register(quotaDenied)
+	_ = registry.Register(quotaBackendErrors)
 	_ = registry.Register(fedForwardFailures)
 }
 
@@ -94,6 +103,10 @@ func IncQuotaDenied(service, kind string) {
 	quotaDenied.WithLabelValues(service, kind).Inc()
 }
 
+func IncQuotaBackendError(scope, op string) {
+	quotaBackendErrors.WithLabelValues(scope, op).Inc()
+}
+
 func IncFederationForwardFailure(service, reason string) {
 	fedForwardFailures.WithLabelValues(service, reason).Inc()
 }
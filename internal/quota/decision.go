@@ -0,0 +1,70 @@
+package quota
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SlotRetryAfter is the Retry-After sent when a tenant has no free concurrency slot. Slots
+// free up as runs finish, which the limiter cannot predict, so clients are asked to poll.
+const SlotRetryAfter = 5 * time.Second
+
+// Decision is the outcome of a QPS check and the state of the tenant's token bucket after
+// it. A zero Limit means the state is unknown (e.g. the backend failed open).
+type Decision struct {
+	Allowed    bool
+	Limit      int           // bucket capacity
+	Remaining  int           // whole requests left in the bucket
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request would be allowed; zero when allowed
+}
+
+// newDecision builds a Decision from the tokens left in a bucket of the given size
+// refilling at rate tokens per second.
+func newDecision(allowed bool, tokens, size, rate float64) Decision {
+	d := Decision{
+		Allowed:   allowed,
+		Limit:     int(size),
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+	}
+	if rate <= 0 {
+		return d
+	}
+	d.Reset = secondsDuration((size - tokens) / rate)
+	if !allowed {
+		d.RetryAfter = secondsDuration((1 - tokens) / rate)
+	}
+	return d
+}
+
+func secondsDuration(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// SetHeaders sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+// for d, and Retry-After when d denied the request. Nothing is set for an unknown state.
+func SetHeaders(h http.Header, d Decision) {
+	if d.Limit <= 0 {
+		return
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	if !d.Allowed {
+		SetRetryAfter(h, d.RetryAfter)
+	}
+}
+
+// SetRetryAfter sets Retry-After to after, rounded up to whole seconds and at least 1.
+func SetRetryAfter(h http.Header, after time.Duration) {
+	h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(after))))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
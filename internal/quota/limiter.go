@@ -3,36 +3,45 @@ package quota
 import (
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
-// Limiter is an in-memory per-tenant quota gate.
-// Phase 7 is intentionally simple; later phases can swap this for Redis / distributed rate limiting.
-type Limiter struct {
-	mu sync.Mutex
-
-	buckets    map[string]*bucket // per-tenant token bucket
-	concurrent map[string]int     // per-tenant concurrent counter
+// Limiter is a per-tenant quota gate for request rate and concurrent runs.
+// The in-memory implementation only limits a single replica; the Redis implementation
+// shares its state so limits hold across replicas.
+type Limiter interface {
+	// AllowQPS consumes one request from the tenant's QPS budget if it is within it, and
+	// reports the state of the budget after the check.
+	AllowQPS(tenant string) Decision
 
-	qps           int
-	maxConcurrent int
-}
-
-type bucket struct {
-	tokens float64
-	last   time.Time
+	// AcquireRunSlot takes a concurrency slot for runID if the tenant is under its limit.
+	// Slots are owned by run ID, so acquiring a slot the run already holds succeeds without
+	// taking another.
+	AcquireRunSlot(tenant, runID string) bool
+	// RestoreRunSlot records the slot of a run admitted earlier (e.g. before a restart, or
+	// handed over by the run it retries), even when that puts the tenant over its limit.
+	RestoreRunSlot(tenant, runID string)
+	// ReleaseRunSlot frees the run's slot and reports whether it held one. Releasing is
+	// idempotent, so every terminal path may release without double-counting.
+	ReleaseRunSlot(tenant, runID string) bool
+	// ConcurrentRuns returns the number of concurrency slots the tenant's runs hold.
+	ConcurrentRuns(tenant string) int
+	// RunSlots returns the IDs of the runs holding a concurrency slot, by tenant, so slots
+	// left behind by runs that ended elsewhere can be reconciled and released.
+	RunSlots() map[string][]string
 }
 
-func NewFromEnv(qpsEnv, concurrentEnv string, defaultQPS, defaultConcurrent int) *Limiter {
-	qps := envInt(qpsEnv, defaultQPS)
-	conc := envInt(concurrentEnv, defaultConcurrent)
-	return &Limiter{
-		buckets:       make(map[string]*bucket),
-		concurrent:    make(map[string]int),
-		qps:           qps,
-		maxConcurrent: conc,
+// NewFromEnv constructs the default limiter for a service, enforcing the limits resolved
+// for each tenant. AGENTOS_QUOTA_REDIS_URL selects the shared Redis backend, with keys
+// namespaced by scope so services sharing a Redis keep separate budgets; otherwise limits
+// are kept in memory.
+func NewFromEnv(scope string, limits LimitsFunc) (Limiter, error) {
+	if url := strings.TrimSpace(os.Getenv("AGENTOS_QUOTA_REDIS_URL")); url != "" {
+		return NewRedisLimiter(url, scope, limits)
 	}
+	return NewMemoryLimiter(limits), nil
 }
 
 func envInt(key string, def int) int {
@@ -47,14 +56,41 @@ func envInt(key string, def int) int {
 	return i
 }
 
-// AllowQPS returns true if a tenant is within its QPS budget.
-func (l *Limiter) AllowQPS(tenant string) bool {
+// memoryLimiter is an in-process Limiter; each replica enforces its own limits.
+type memoryLimiter struct {
+	mu sync.Mutex
+
+	buckets map[string]*bucket             // per-tenant token bucket
+	slots   map[string]map[string]struct{} // per-tenant run IDs holding a concurrency slot
+
+	limits LimitsFunc
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryLimiter returns an in-memory Limiter enforcing the limits resolved for each
+// tenant.
+func NewMemoryLimiter(limits LimitsFunc) Limiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*bucket),
+		slots:   make(map[string]map[string]struct{}),
+		limits:  limits,
+	}
+}
+
+func (l *memoryLimiter) AllowQPS(tenant string) Decision {
+	limits := l.limits(tenant)
+	size := limits.bucketSize()
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	b := l.buckets[tenant]
 	if b == nil {
-		b = &bucket{tokens: float64(l.qps), last: time.Now()}
+		b = &bucket{tokens: size, last: time.Now()}
 		l.buckets[tenant] = b
 	}
 	now := time.Now()
@@ -62,38 +98,77 @@ func (l *Limiter) AllowQPS(tenant string) bool {
 	b.last = now
 
 	// refill
-	b.tokens += dt * float64(l.qps)
-	if b.tokens > float64(l.qps) {
-		b.tokens = float64(l.qps)
+	b.tokens += dt * limits.QPS
+	if b.tokens > size {
+		b.tokens = size
+	}
+
+	allowed := b.tokens >= 1.0
+	if allowed {
+		b.tokens -= 1.0
 	}
+	return newDecision(allowed, b.tokens, size, limits.QPS)
+}
+
+func (l *memoryLimiter) AcquireRunSlot(tenant, runID string) bool {
+	maxConcurrent := l.limits(tenant).MaxConcurrent
 
-	if b.tokens < 1.0 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	held := l.slots[tenant]
+	if _, ok := held[runID]; ok {
+		return true
+	}
+	if len(held) >= maxConcurrent {
 		return false
 	}
-	b.tokens -= 1.0
+	if held == nil {
+		held = make(map[string]struct{})
+		l.slots[tenant] = held
+	}
+	held[runID] = struct{}{}
 	return true
 }
 
-// TryIncConcurrent increments concurrent count if under max.
-func (l *Limiter) TryIncConcurrent(tenant string) bool {
+func (l *memoryLimiter) RestoreRunSlot(tenant, runID string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	cur := l.concurrent[tenant]
-	if cur >= l.maxConcurrent {
+	held := l.slots[tenant]
+	if held == nil {
+		held = make(map[string]struct{})
+		l.slots[tenant] = held
+	}
+	held[runID] = struct{}{}
+}
+
+func (l *memoryLimiter) ReleaseRunSlot(tenant, runID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	held := l.slots[tenant]
+	if _, ok := held[runID]; !ok {
 		return false
 	}
-	l.concurrent[tenant] = cur + 1
+	delete(held, runID)
+	if len(held) == 0 {
+		delete(l.slots, tenant)
+	}
 	return true
 }
 
-// DecConcurrent decrements concurrent count (floor at 0).
-func (l *Limiter) DecConcurrent(tenant string) {
+func (l *memoryLimiter) ConcurrentRuns(tenant string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.slots[tenant])
+}
+
+func (l *memoryLimiter) RunSlots() map[string][]string {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	cur := l.concurrent[tenant]
-	cur--
-	if cur < 0 {
-		cur = 0
+	slots := make(map[string][]string, len(l.slots))
+	for tenant, held := range l.slots {
+		for runID := range held {
+			slots[tenant] = append(slots[tenant], runID)
+		}
 	}
-	l.concurrent[tenant] = cur
+	return slots
 }
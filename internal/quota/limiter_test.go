@@ -0,0 +1,99 @@
+package quota
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	testLimiter(t, NewMemoryLimiter(testLimits))
+}
+
+var testLimits = Fixed(Limits{QPS: 2, MaxConcurrent: 2})
+
+// testLimiter exercises a Limiter allowing 2 QPS and 2 concurrent runs per tenant.
+func testLimiter(t *testing.T, l Limiter) {
+	t.Helper()
+
+	first := l.AllowQPS("tnt_a")
+	if !first.Allowed || first.Limit != 2 || first.Remaining != 1 || first.RetryAfter != 0 {
+		t.Fatalf("expected 1 of 2 requests left after the first, got %+v", first)
+	}
+	if !l.AllowQPS("tnt_a").Allowed {
+		t.Fatalf("expected the second request within the QPS budget")
+	}
+	denied := l.AllowQPS("tnt_a")
+	if denied.Allowed || denied.Remaining != 0 {
+		t.Fatalf("expected the QPS budget of 2 to be enforced, got %+v", denied)
+	}
+	if denied.RetryAfter <= 0 || denied.RetryAfter > 500*time.Millisecond || denied.Reset < denied.RetryAfter || denied.Reset > time.Second {
+		t.Fatalf("expected a retry within one refill at 2 QPS, got %+v", denied)
+	}
+	if !l.AllowQPS("tnt_b").Allowed {
+		t.Fatalf("expected QPS budgets to be per tenant")
+	}
+
+	if !l.AcquireRunSlot("tnt_a", "run_1") || !l.AcquireRunSlot("tnt_a", "run_1") {
+		t.Fatalf("expected acquiring a held slot to succeed")
+	}
+	if got := l.ConcurrentRuns("tnt_a"); got != 1 {
+		t.Fatalf("expected re-acquire not to take a second slot, got %d", got)
+	}
+	if !l.AcquireRunSlot("tnt_a", "run_2") || l.AcquireRunSlot("tnt_a", "run_3") {
+		t.Fatalf("expected the limit of 2 to be enforced")
+	}
+	if !l.AcquireRunSlot("tnt_b", "run_3") {
+		t.Fatalf("expected slots to be per tenant")
+	}
+
+	if !l.ReleaseRunSlot("tnt_a", "run_1") || l.ReleaseRunSlot("tnt_a", "run_1") {
+		t.Fatalf("expected a slot to be released exactly once")
+	}
+	if l.ReleaseRunSlot("tnt_b", "run_2") {
+		t.Fatalf("expected releasing another tenant's run to be a no-op")
+	}
+	if got := l.ConcurrentRuns("tnt_a"); got != 1 {
+		t.Fatalf("expected one slot left after double release, got %d", got)
+	}
+
+	l.RestoreRunSlot("tnt_a", "run_4")
+	l.RestoreRunSlot("tnt_a", "run_5")
+	if got := l.ConcurrentRuns("tnt_a"); got != 3 {
+		t.Fatalf("expected restored slots to bypass the limit, got %d", got)
+	}
+	if l.AcquireRunSlot("tnt_a", "run_6") {
+		t.Fatalf("expected no new slots while over the limit")
+	}
+
+	slots := l.RunSlots()
+	sort.Strings(slots["tnt_a"])
+	if got := strings.Join(slots["tnt_a"], ","); got != "run_2,run_4,run_5" || len(slots) != 2 || len(slots["tnt_b"]) != 1 {
+		t.Fatalf("expected the slot holders by tenant, got %v", slots)
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	h := http.Header{}
+	SetHeaders(h, Decision{Allowed: false, Limit: 5, Remaining: 0, Reset: 2100 * time.Millisecond, RetryAfter: 100 * time.Millisecond})
+	want := map[string]string{"RateLimit-Limit": "5", "RateLimit-Remaining": "0", "RateLimit-Reset": "3", "Retry-After": "1"}
+	for k, v := range want {
+		if got := h.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	h = http.Header{}
+	SetHeaders(h, Decision{Allowed: true, Limit: 5, Remaining: 4, Reset: 200 * time.Millisecond})
+	if h.Get("Retry-After") != "" || h.Get("RateLimit-Remaining") != "4" {
+		t.Fatalf("expected rate limit headers without Retry-After, got %v", h)
+	}
+
+	h = http.Header{}
+	SetHeaders(h, Decision{Allowed: true})
+	if len(h) != 0 {
+		t.Fatalf("expected no headers for an unknown bucket state, got %v", h)
+	}
+}
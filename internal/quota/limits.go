@@ -0,0 +1,157 @@
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// Limits are the quotas a Limiter enforces for one tenant.
+type Limits struct {
+	QPS           float64 // sustained requests per second
+	Burst         int     // bucket size; 0 means ceil(QPS)
+	MaxConcurrent int
+}
+
+// bucketSize returns the token bucket capacity for the limits.
+func (l Limits) bucketSize() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.QPS))
+}
+
+// LimitsFunc resolves a tenant's limits. Limiters call it on every check, so changes to
+// the underlying tenant configuration apply to the next request.
+type LimitsFunc func(tenant string) Limits
+
+// Fixed returns a LimitsFunc applying the same limits to every tenant.
+func Fixed(limits Limits) LimitsFunc {
+	return func(string) Limits { return limits }
+}
+
+// QuotaKeys name the Tenant.Quotas entries ("section.key", as in runs.run_create_qps)
+// that override each limit. Empty keys are never overridden.
+type QuotaKeys struct {
+	QPS           string
+	Burst         string
+	MaxConcurrent string
+}
+
+var (
+	// RunQuotaKeys are the run creation quotas enforced by agent-orchestrator.
+	RunQuotaKeys = QuotaKeys{QPS: "runs.run_create_qps", Burst: "runs.run_create_burst", MaxConcurrent: "runs.max_concurrent_runs"}
+	// InvokeQuotaKeys are the model invocation quotas enforced by model-policy.
+	InvokeQuotaKeys = QuotaKeys{QPS: "models.invoke_qps", Burst: "models.invoke_burst"}
+)
+
+// TenantLookup returns the current configuration of a tenant.
+type TenantLookup func(tenantID string) (types.Tenant, bool)
+
+// TenantLimitsFromEnv returns a LimitsFunc that resolves each limit from the tenant's
+// quotas, then from the quotas of its plan tier in AGENTOS_QUOTA_PLAN_TIERS (a JSON object
+// of tier name to quotas, e.g. {"standard":{"runs":{"run_create_qps":25}}}), then from the
+// env defaults.
+func TenantLimitsFromEnv(lookup TenantLookup, keys QuotaKeys, qpsEnv, concurrentEnv string, defaultQPS, defaultConcurrent int) (LimitsFunc, error) {
+	defaults := Limits{QPS: float64(envInt(qpsEnv, defaultQPS)), MaxConcurrent: envInt(concurrentEnv, defaultConcurrent)}
+	tiers := map[string]map[string]any{}
+	if raw := strings.TrimSpace(os.Getenv("AGENTOS_QUOTA_PLAN_TIERS")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &tiers); err != nil {
+			return nil, fmt.Errorf("AGENTOS_QUOTA_PLAN_TIERS: %w", err)
+		}
+		for tier, quotas := range tiers {
+			if err := ValidateQuotas(quotas); err != nil {
+				return nil, fmt.Errorf("AGENTOS_QUOTA_PLAN_TIERS[%s]: %w", tier, err)
+			}
+		}
+	}
+	return TenantLimits(lookup, keys, tiers, defaults), nil
+}
+
+// TenantLimits returns a LimitsFunc that resolves each limit from the tenant's quotas,
+// then from tiers[tenant.PlanTier], then from defaults.
+func TenantLimits(lookup TenantLookup, keys QuotaKeys, tiers map[string]map[string]any, defaults Limits) LimitsFunc {
+	return func(tenantID string) Limits {
+		limits := defaults
+		tenant, ok := lookup(tenantID)
+		if !ok {
+			return limits
+		}
+		for _, quotas := range []map[string]any{tiers[tenant.PlanTier], tenant.Quotas} {
+			if v, ok := quotaValue(quotas, keys.QPS); ok {
+				limits.QPS = v
+			}
+			if v, ok := quotaValue(quotas, keys.Burst); ok {
+				limits.Burst = int(v)
+			}
+			if v, ok := quotaValue(quotas, keys.MaxConcurrent); ok {
+				limits.MaxConcurrent = int(v)
+			}
+		}
+		return limits
+	}
+}
+
+// ValidateQuotas checks that every limit a Limiter reads from quotas is a positive number.
+// Other quota entries are left to the services that enforce them.
+func ValidateQuotas(quotas map[string]any) error {
+	for _, keys := range []QuotaKeys{RunQuotaKeys, InvokeQuotaKeys} {
+		for _, key := range []string{keys.QPS, keys.Burst, keys.MaxConcurrent} {
+			if key == "" {
+				continue
+			}
+			raw, ok := quotaEntry(quotas, key)
+			if !ok {
+				continue
+			}
+			if v, ok := quotaNumber(raw); !ok || v <= 0 {
+				return fmt.Errorf("quotas.%s must be a positive number", key)
+			}
+		}
+	}
+	return nil
+}
+
+// quotaValue returns the positive number at key ("section.name") in quotas.
+func quotaValue(quotas map[string]any, key string) (float64, bool) {
+	raw, ok := quotaEntry(quotas, key)
+	if !ok {
+		return 0, false
+	}
+	v, ok := quotaNumber(raw)
+	if !ok || v <= 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+func quotaEntry(quotas map[string]any, key string) (any, bool) {
+	if quotas == nil || key == "" {
+		return nil, false
+	}
+	section, name, _ := strings.Cut(key, ".")
+	m, ok := quotas[section].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	v, ok := m[name]
+	return v, ok
+}
+
+func quotaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,72 @@
+package quota
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestTenantLimitsResolutionOrder(t *testing.T) {
+	t.Setenv("AGENTOS_QUOTA_RUN_CREATE_QPS", "10")
+	t.Setenv("AGENTOS_QUOTA_PLAN_TIERS", `{"standard":{"runs":{"run_create_qps":25,"max_concurrent_runs":50}}}`)
+
+	var mu sync.Mutex
+	tenants := map[string]types.Tenant{
+		"tnt_free":     {TenantID: "tnt_free", PlanTier: "free"},
+		"tnt_standard": {TenantID: "tnt_standard", PlanTier: "standard"},
+		"tnt_custom": {TenantID: "tnt_custom", PlanTier: "standard", Quotas: map[string]any{
+			"runs": map[string]any{"max_concurrent_runs": float64(3), "run_create_burst": float64(6)},
+		}},
+	}
+	lookup := func(id string) (types.Tenant, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		t, ok := tenants[id]
+		return t, ok
+	}
+	limits, err := TenantLimitsFromEnv(lookup, RunQuotaKeys, "AGENTOS_QUOTA_RUN_CREATE_QPS", "AGENTOS_QUOTA_CONCURRENT_RUNS", 1, 25)
+	if err != nil {
+		t.Fatalf("TenantLimitsFromEnv: %v", err)
+	}
+
+	cases := map[string]Limits{
+		"tnt_unknown":  {QPS: 10, MaxConcurrent: 25},
+		"tnt_free":     {QPS: 10, MaxConcurrent: 25},
+		"tnt_standard": {QPS: 25, MaxConcurrent: 50},
+		"tnt_custom":   {QPS: 25, Burst: 6, MaxConcurrent: 3},
+	}
+	for tenant, want := range cases {
+		if got := limits(tenant); got != want {
+			t.Errorf("%s: limits = %+v, want %+v", tenant, got, want)
+		}
+	}
+
+	// limits follow tenant changes without being rebuilt
+	mu.Lock()
+	tenants["tnt_free"] = types.Tenant{TenantID: "tnt_free", PlanTier: "standard"}
+	mu.Unlock()
+	if got := limits("tnt_free"); got.QPS != 25 || got.MaxConcurrent != 50 {
+		t.Fatalf("expected plan tier change to apply, got %+v", got)
+	}
+
+	l := NewMemoryLimiter(limits)
+	for i := 0; i < 3; i++ {
+		if !l.AcquireRunSlot("tnt_custom", string(rune('a'+i))) {
+			t.Fatalf("expected slot %d within the tenant override", i)
+		}
+	}
+	if l.AcquireRunSlot("tnt_custom", "d") {
+		t.Fatalf("expected the tenant override of 3 concurrent runs to be enforced")
+	}
+}
+
+func TestTenantLimitsFromEnvRejectsInvalidPlanTiers(t *testing.T) {
+	lookup := func(string) (types.Tenant, bool) { return types.Tenant{}, false }
+	for _, raw := range []string{`not json`, `{"standard":{"runs":{"run_create_qps":0}}}`, `{"standard":{"runs":{"max_concurrent_runs":"ten"}}}`} {
+		t.Setenv("AGENTOS_QUOTA_PLAN_TIERS", raw)
+		if _, err := TenantLimitsFromEnv(lookup, RunQuotaKeys, "", "", 1, 1); err == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
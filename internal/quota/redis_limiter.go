@@ -0,0 +1,177 @@
+package quota
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/metrics"
+)
+
+// redisTimeout bounds every Redis round trip so a slow backend cannot stall requests.
+const redisTimeout = 500 * time.Millisecond
+
+// allowQPSScript is the token bucket of memoryLimiter.AllowQPS, evaluated atomically in
+// Redis against the server clock so every replica draws from the same bucket. Keys expire
+// once an idle bucket would have refilled. It returns whether the request was allowed and
+// the tokens left, as a string since Redis truncates Lua numbers to integers.
+var allowQPSScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local size = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(b[1])
+local last = tonumber(b[2])
+if tokens == nil or last == nil then
+	tokens = size
+	last = now
+end
+tokens = math.min(size, tokens + math.max(0, now - last) / 1000000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(size / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// acquireSlotScript adds ARGV[1] to the tenant's slot set unless the set already holds
+// ARGV[2] other runs.
+var acquireSlotScript = redis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 1 then
+	return 1
+end
+if redis.call('SCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SADD', KEYS[1], ARGV[1])
+return 1
+`)
+
+// redisLimiter is a Limiter whose buckets and slots live in Redis, shared by every replica
+// configured with the same URL and scope. Slots are kept as one set of run IDs per tenant.
+//
+// When Redis is unreachable, AllowQPS fails open and AcquireRunSlot fails closed: rate
+// limiting is best effort, but the concurrency limit is never exceeded. A failed release
+// leaves the slot held until the run's slot is next released, or until an orchestrator
+// restart reconciles the slots against the runs still unfinished.
+type redisLimiter struct {
+	client *redis.Client
+	scope  string
+	limits LimitsFunc
+}
+
+// NewRedisLimiter returns a Limiter backed by the Redis server at url
+// (redis://[:password@]host:port/db or rediss:// for TLS). Keys are prefixed with
+// agentos:quota:{scope}: so services can share a server. The server must be reachable.
+// Limits are resolved per check on the calling replica and passed to Redis with it.
+func NewRedisLimiter(url, scope string, limits LimitsFunc) (Limiter, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &redisLimiter{client: client, scope: scope, limits: limits}, nil
+}
+
+func (l *redisLimiter) AllowQPS(tenant string) Decision {
+	limits := l.limits(tenant)
+	size := limits.bucketSize()
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	res, err := allowQPSScript.Run(ctx, l.client, []string{l.key("qps", tenant)}, limits.QPS, size).Slice()
+	if err != nil || len(res) != 2 {
+		metrics.IncQuotaBackendError(l.scope, "allow_qps")
+		return Decision{Allowed: true}
+	}
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		metrics.IncQuotaBackendError(l.scope, "allow_qps")
+		return Decision{Allowed: allowed == 1}
+	}
+	return newDecision(allowed == 1, tokens, size, limits.QPS)
+}
+
+func (l *redisLimiter) AcquireRunSlot(tenant, runID string) bool {
+	maxConcurrent := l.limits(tenant).MaxConcurrent
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	acquired, err := acquireSlotScript.Run(ctx, l.client, []string{l.key("slots", tenant)}, runID, maxConcurrent).Int()
+	if err != nil {
+		metrics.IncQuotaBackendError(l.scope, "acquire_slot")
+		return false
+	}
+	return acquired == 1
+}
+
+func (l *redisLimiter) RestoreRunSlot(tenant, runID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := l.client.SAdd(ctx, l.key("slots", tenant), runID).Err(); err != nil {
+		metrics.IncQuotaBackendError(l.scope, "restore_slot")
+	}
+}
+
+func (l *redisLimiter) ReleaseRunSlot(tenant, runID string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	removed, err := l.client.SRem(ctx, l.key("slots", tenant), runID).Result()
+	if err != nil {
+		metrics.IncQuotaBackendError(l.scope, "release_slot")
+		return false
+	}
+	return removed == 1
+}
+
+func (l *redisLimiter) ConcurrentRuns(tenant string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	n, err := l.client.SCard(ctx, l.key("slots", tenant)).Result()
+	if err != nil {
+		metrics.IncQuotaBackendError(l.scope, "concurrent_runs")
+		return 0
+	}
+	return int(n)
+}
+
+func (l *redisLimiter) RunSlots() map[string][]string {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	prefix := l.key("slots", "")
+	slots := make(map[string][]string)
+	iter := l.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		runIDs, err := l.client.SMembers(ctx, key).Result()
+		if err != nil {
+			metrics.IncQuotaBackendError(l.scope, "run_slots")
+			return nil
+		}
+		if len(runIDs) > 0 {
+			slots[strings.TrimPrefix(key, prefix)] = runIDs
+		}
+	}
+	if err := iter.Err(); err != nil {
+		metrics.IncQuotaBackendError(l.scope, "run_slots")
+		return nil
+	}
+	return slots
+}
+
+func (l *redisLimiter) key(kind, tenant string) string {
+	return "agentos:quota:" + l.scope + ":" + kind + ":" + tenant
+}
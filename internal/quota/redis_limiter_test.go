@@ -0,0 +1,74 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisLimiter(t *testing.T, url, scope string) Limiter {
+	t.Helper()
+	l, err := NewRedisLimiter(url, scope, testLimits)
+	if err != nil {
+		t.Fatalf("NewRedisLimiter: %v", err)
+	}
+	return l
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	testLimiter(t, newTestRedisLimiter(t, "redis://"+mr.Addr(), "test"))
+}
+
+func TestRedisLimiterSharesLimitsAcrossReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	url := "redis://" + mr.Addr()
+	a := newTestRedisLimiter(t, url, "agent-orchestrator")
+	b := newTestRedisLimiter(t, url, "agent-orchestrator")
+	other := newTestRedisLimiter(t, url, "model-policy")
+
+	if !a.AllowQPS("tnt_a").Allowed || !b.AllowQPS("tnt_a").Allowed || a.AllowQPS("tnt_a").Allowed || b.AllowQPS("tnt_a").Allowed {
+		t.Fatalf("expected replicas to draw from one QPS budget")
+	}
+	if !other.AllowQPS("tnt_a").Allowed {
+		t.Fatalf("expected scopes to keep separate QPS budgets")
+	}
+
+	if !a.AcquireRunSlot("tnt_a", "run_1") || !b.AcquireRunSlot("tnt_a", "run_2") || a.AcquireRunSlot("tnt_a", "run_3") {
+		t.Fatalf("expected replicas to share the concurrency limit")
+	}
+	if got := other.ConcurrentRuns("tnt_a"); got != 0 {
+		t.Fatalf("expected scopes to keep separate slots, got %d", got)
+	}
+	if !b.ReleaseRunSlot("tnt_a", "run_1") || a.ReleaseRunSlot("tnt_a", "run_1") {
+		t.Fatalf("expected a slot taken on one replica to be released once on any replica")
+	}
+	if !a.AcquireRunSlot("tnt_a", "run_3") {
+		t.Fatalf("expected the released slot to be available to every replica")
+	}
+}
+
+func TestRedisLimiterFailsClosedForSlots(t *testing.T) {
+	mr := miniredis.RunT(t)
+	l := newTestRedisLimiter(t, "redis://"+mr.Addr(), "test")
+	mr.Close()
+
+	if l.AcquireRunSlot("tnt_a", "run_1") {
+		t.Fatalf("expected slot acquisition to fail while Redis is down")
+	}
+	if !l.AllowQPS("tnt_a").Allowed {
+		t.Fatalf("expected QPS checks to fail open while Redis is down")
+	}
+}
+
+func TestNewRedisLimiterRequiresReachableServer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	if _, err := NewRedisLimiter("redis://"+addr, "test", testLimits); err == nil {
+		t.Fatalf("expected an error for an unreachable server")
+	}
+	if _, err := NewRedisLimiter("not a url", "test", testLimits); err == nil {
+		t.Fatalf("expected an error for an invalid URL")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -19,6 +21,8 @@ var (
 	ErrAgentNotFound = errors.New("agent not found")
 	// ErrInvalidAgent signals missing required agent identity fields.
 	ErrInvalidAgent = errors.New("invalid agent")
+	// ErrAgentVersionExists signals attempts to overwrite an immutable agent version.
+	ErrAgentVersionExists = errors.New("agent version already exists")
 )
 
 // AgentStore is a tenant-scoped persistence port for agent metadata.
@@ -27,6 +31,13 @@ type AgentStore interface {
 	Get(ctx context.Context, tenantID, agentID string) (types.Agent, bool, error)
 	List(ctx context.Context, tenantID string) ([]types.Agent, error)
 	Save(ctx context.Context, agent types.Agent) error
+
+	// CreateVersion records an immutable agent version; existing versions are never
+	// overwritten (ErrAgentVersionExists).
+	CreateVersion(ctx context.Context, version types.AgentVersion) error
+	GetVersion(ctx context.Context, tenantID, agentID, version string) (types.AgentVersion, bool, error)
+	// ListVersions returns the agent's versions, oldest first.
+	ListVersions(ctx context.Context, tenantID, agentID string) ([]types.AgentVersion, error)
 }
 
 // fileAgentStore is a file-based implementation of AgentStore.
@@ -36,8 +47,13 @@ type fileAgentStore struct {
 	agents map[string]types.Agent // key: tenant/{tenant_id}/agents/{agent_id}
 }
 
-// NewAgentStoreFromEnv constructs the default agent store adapter.
+// NewAgentStoreFromEnv constructs the default agent store adapter. AGENTOS_AGENT_STORE_DSN
+// selects the SQL adapter (it may point at the same database as AGENTOS_RUN_STORE_DSN);
+// otherwise agents are kept as files under AGENTOS_AGENT_STORE_DIR.
 func NewAgentStoreFromEnv() (AgentStore, error) {
+	if dsn := strings.TrimSpace(os.Getenv("AGENTOS_AGENT_STORE_DSN")); dsn != "" {
+		return NewSQLAgentStore(dsn)
+	}
 	dir := strings.TrimSpace(os.Getenv("AGENTOS_AGENT_STORE_DIR"))
 	if dir == "" {
 		dir = filepath.Join("data", "agents")
@@ -128,6 +144,128 @@ func (s *fileAgentStore) Save(ctx context.Context, agent types.Agent) error {
 	return s.persistAgent(agent)
 }
 
+func (s *fileAgentStore) CreateVersion(ctx context.Context, version types.AgentVersion) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if version.TenantID == "" || version.AgentID == "" || version.Version == "" {
+		return ErrInvalidAgent
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir == "" {
+		return nil
+	}
+
+	// Write file: data/agents/{tenant_id}/_versions/{agent_id}/{version}.json
+	dir := s.versionDir(version.TenantID, version.AgentID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(version, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, version.Version+".json"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return ErrAgentVersionExists
+		}
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *fileAgentStore) GetVersion(ctx context.Context, tenantID, agentID, version string) (types.AgentVersion, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.AgentVersion{}, false, err
+	}
+	if tenantID == "" || agentID == "" || version == "" || s.dir == "" || strings.ContainsAny(version, `/\`) {
+		return types.AgentVersion{}, false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := os.ReadFile(filepath.Join(s.versionDir(tenantID, agentID), version+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return types.AgentVersion{}, false, nil
+	}
+	if err != nil {
+		return types.AgentVersion{}, false, err
+	}
+	var v types.AgentVersion
+	if err := json.Unmarshal(b, &v); err != nil {
+		return types.AgentVersion{}, false, err
+	}
+	return v, true, nil
+}
+
+func (s *fileAgentStore) ListVersions(ctx context.Context, tenantID, agentID string) ([]types.AgentVersion, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" || agentID == "" || s.dir == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.versionDir(tenantID, agentID)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []types.AgentVersion
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var v types.AgentVersion
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	sortAgentVersions(versions)
+	return versions, nil
+}
+
+func (s *fileAgentStore) versionDir(tenantID, agentID string) string {
+	return filepath.Join(s.dir, tenantID, agentVersionsDir, agentID)
+}
+
+// agentVersionsDir holds version snapshots inside each tenant directory; load skips it.
+const agentVersionsDir = "_versions"
+
+// sortAgentVersions orders versions by their numeric version, oldest first. Versions that
+// are not numbers (e.g. agents registered before versioning) sort first by CreatedAt.
+func sortAgentVersions(versions []types.AgentVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		ni, erri := strconv.Atoi(versions[i].Version)
+		nj, errj := strconv.Atoi(versions[j].Version)
+		switch {
+		case erri == nil && errj == nil:
+			return ni < nj
+		case erri != nil && errj != nil:
+			return versions[i].CreatedAt < versions[j].CreatedAt
+		default:
+			return erri != nil
+		}
+	})
+}
+
 func (s *fileAgentStore) load() error {
 	if s.dir == "" {
 		return nil
@@ -141,6 +279,9 @@ func (s *fileAgentStore) load() error {
 			}
 			return err
 		}
+		if info.IsDir() && info.Name() == agentVersionsDir {
+			return filepath.SkipDir
+		}
 		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
 			return nil
 		}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestFileAgentStoreVersionsAreImmutable(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "agents")
+
+	store, err := NewFileAgentStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileAgentStore error: %v", err)
+	}
+	testAgentStoreVersions(t, store)
+
+	// version snapshots must not be picked up as agents on reload
+	reloaded, err := NewFileAgentStore(dir)
+	if err != nil {
+		t.Fatalf("reload store error: %v", err)
+	}
+	agents, err := reloaded.List(ctx, "tnt_alpha")
+	if err != nil || len(agents) != 1 || agents[0].Version != "2" {
+		t.Fatalf("expected the single current agent after reload, got %+v err=%v", agents, err)
+	}
+	if versions, _ := reloaded.ListVersions(ctx, "tnt_alpha", "agt_demo"); len(versions) != 2 {
+		t.Fatalf("expected versions after reload, got %d", len(versions))
+	}
+}
+
+// testAgentStoreVersions checks version immutability, ordering and tenant scoping against
+// any AgentStore.
+func testAgentStoreVersions(t *testing.T, store AgentStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	agent := types.Agent{TenantID: "tnt_alpha", AgentID: "agt_demo", Name: "Demo", Version: "2", Status: "active"}
+	if err := store.Save(ctx, agent); err != nil {
+		t.Fatalf("Save agent error: %v", err)
+	}
+	for _, v := range []string{"2", "1"} {
+		version := types.AgentVersion{TenantID: "tnt_alpha", AgentID: "agt_demo", Version: v, Name: "Demo v" + v}
+		if err := store.CreateVersion(ctx, version); err != nil {
+			t.Fatalf("CreateVersion %s error: %v", v, err)
+		}
+	}
+	if err := store.CreateVersion(ctx, types.AgentVersion{TenantID: "tnt_alpha", AgentID: "agt_demo", Version: "1", Name: "rewritten"}); !errors.Is(err, ErrAgentVersionExists) {
+		t.Fatalf("expected ErrAgentVersionExists, got %v", err)
+	}
+
+	versions, err := store.ListVersions(ctx, "tnt_alpha", "agt_demo")
+	if err != nil || len(versions) != 2 || versions[0].Version != "1" || versions[1].Version != "2" {
+		t.Fatalf("expected versions 1 and 2 in order, got %+v err=%v", versions, err)
+	}
+	v1, ok, err := store.GetVersion(ctx, "tnt_alpha", "agt_demo", "1")
+	if err != nil || !ok || v1.Name != "Demo v1" {
+		t.Fatalf("expected untouched version 1, got %+v ok=%v err=%v", v1, ok, err)
+	}
+	if _, ok, _ := store.GetVersion(ctx, "tnt_beta", "agt_demo", "1"); ok {
+		t.Fatalf("expected tenant isolation on GetVersion")
+	}
+	if versions, _ := store.ListVersions(ctx, "tnt_beta", "agt_demo"); len(versions) != 0 {
+		t.Fatalf("expected tenant isolation on ListVersions, got %d", len(versions))
+	}
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/id"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// ErrInvalidEvent signals missing required event identity fields.
+var ErrInvalidEvent = errors.New("invalid event")
+
+// EventStore is a tenant-scoped, append-only persistence port for run events.
+type EventStore interface {
+	// Append assigns the next sequence number for the event's (tenant, run) log, filling
+	// in EventID and Time when empty, persists it and returns the stored event.
+	Append(ctx context.Context, event types.Event) (types.Event, error)
+	// List returns the run's events with a sequence greater than afterSequence, in order.
+	List(ctx context.Context, tenantID, runID string, afterSequence int) ([]types.Event, error)
+}
+
+// NewEventStoreFromEnv constructs the default event store adapter, using AGENTOS_EVENT_STORE_DIR
+// or falling back to ./data/agent-orchestrator/events. AGENTOS_EVENT_CACHE_RUNS bounds the
+// number of run logs kept in memory (default 1024).
+func NewEventStoreFromEnv() (EventStore, error) {
+	dir := strings.TrimSpace(os.Getenv("AGENTOS_EVENT_STORE_DIR"))
+	if dir == "" {
+		dir = filepath.Join("data", "agent-orchestrator", "events")
+	}
+	store, err := NewFileEventStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := strconv.Atoi(os.Getenv("AGENTOS_EVENT_CACHE_RUNS")); err == nil && n > 0 {
+		store.(*fileEventStore).maxLogs = n
+	}
+	return store, nil
+}
+
+// fileEventStore keeps one JSON-lines file per run under {dir}/{tenant_id}/{run_id}.jsonl.
+// Logs are loaded on first access and cached in memory, least recently used first out
+// once more than maxLogs are cached; an evicted log is read back from its file.
+type fileEventStore struct {
+	mu      sync.Mutex
+	dir     string
+	maxLogs int
+	logs    map[string]*list.Element // key: tenant/{tenant_id}/runs/{run_id}
+	lru     *list.List               // of *cachedEventLog, most recently used first
+}
+
+type cachedEventLog struct {
+	key    string
+	events []types.Event
+}
+
+// NewFileEventStore returns a file-backed EventStore.
+func NewFileEventStore(dir string) (EventStore, error) {
+	if dir == "" {
+		dir = filepath.Join("data", "agent-orchestrator", "events")
+	}
+	return &fileEventStore{
+		dir:     dir,
+		maxLogs: 1024,
+		logs:    make(map[string]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+func (s *fileEventStore) Append(ctx context.Context, event types.Event) (types.Event, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Event{}, err
+	}
+	if event.TenantID == "" || event.RunID == "" || event.Type == "" {
+		return types.Event{}, ErrInvalidEvent
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.loadLocked(event.TenantID, event.RunID)
+	if err != nil {
+		return types.Event{}, err
+	}
+	event.Sequence = 1
+	if n := len(events); n > 0 {
+		event.Sequence = events[n-1].Sequence + 1
+	}
+	if event.EventID == "" {
+		event.EventID = id.New("evt")
+	}
+	if event.Time == "" {
+		event.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	if event.Payload == nil {
+		event.Payload = map[string]any{}
+	}
+
+	if err := s.persistLocked(event); err != nil {
+		return types.Event{}, err
+	}
+	s.cacheLocked(storageKey(event.TenantID, event.RunID), append(events, event))
+	return event, nil
+}
+
+func (s *fileEventStore) List(ctx context.Context, tenantID, runID string, afterSequence int) ([]types.Event, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" || runID == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.loadLocked(tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	var out []types.Event
+	for _, ev := range events {
+		if ev.Sequence > afterSequence {
+			out = append(out, ev)
+		}
+	}
+	return out, nil
+}
+
+func (s *fileEventStore) loadLocked(tenantID, runID string) ([]types.Event, error) {
+	key := storageKey(tenantID, runID)
+	if el, ok := s.logs[key]; ok {
+		s.lru.MoveToFront(el)
+		return el.Value.(*cachedEventLog).events, nil
+	}
+	var events []types.Event
+	if s.dir != "" {
+		f, err := os.Open(s.eventPath(tenantID, runID))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" {
+					continue
+				}
+				var ev types.Event
+				if err := json.Unmarshal([]byte(line), &ev); err != nil {
+					return nil, err
+				}
+				events = append(events, ev)
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	s.cacheLocked(key, events)
+	return events, nil
+}
+
+// cacheLocked stores a run's log as the most recently used and evicts the least recently
+// used logs beyond maxLogs. Logs are only evicted when they can be read back from disk.
+func (s *fileEventStore) cacheLocked(key string, events []types.Event) {
+	if el, ok := s.logs[key]; ok {
+		el.Value.(*cachedEventLog).events = events
+		s.lru.MoveToFront(el)
+	} else {
+		s.logs[key] = s.lru.PushFront(&cachedEventLog{key: key, events: events})
+	}
+	for s.dir != "" && s.maxLogs > 0 && s.lru.Len() > s.maxLogs {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.logs, oldest.Value.(*cachedEventLog).key)
+	}
+}
+
+func (s *fileEventStore) persistLocked(event types.Event) error {
+	if s.dir == "" {
+		return nil
+	}
+	path := s.eventPath(event.TenantID, event.RunID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *fileEventStore) eventPath(tenantID, runID string) string {
+	return filepath.Join(s.dir, tenantID, runID+".jsonl")
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestFileEventStoreAssignsSequencesAndReplaysAfterReload(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "events")
+
+	store, err := NewFileEventStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileEventStore error: %v", err)
+	}
+
+	for _, typ := range []string{"agentos.run.created", "agentos.run.started", "agentos.run.completed"} {
+		if _, err := store.Append(ctx, types.Event{TenantID: "tnt_alpha", RunID: "run_1", Type: typ}); err != nil {
+			t.Fatalf("Append %s error: %v", typ, err)
+		}
+	}
+	other, err := store.Append(ctx, types.Event{TenantID: "tnt_alpha", RunID: "run_2", Type: "agentos.run.created"})
+	if err != nil {
+		t.Fatalf("Append other run error: %v", err)
+	}
+	if other.Sequence != 1 {
+		t.Fatalf("expected sequences to be per run, got %d", other.Sequence)
+	}
+
+	reloaded, err := NewFileEventStore(dir)
+	if err != nil {
+		t.Fatalf("reload store error: %v", err)
+	}
+	events, err := reloaded.List(ctx, "tnt_alpha", "run_1", 0)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events after reload, got %d", len(events))
+	}
+	for i, ev := range events {
+		if ev.Sequence != i+1 {
+			t.Fatalf("expected sequence %d, got %d", i+1, ev.Sequence)
+		}
+		if ev.EventID == "" || ev.Time == "" {
+			t.Fatalf("expected event_id and time to be assigned, got %+v", ev)
+		}
+	}
+
+	next, err := reloaded.Append(ctx, types.Event{TenantID: "tnt_alpha", RunID: "run_1", Type: "agentos.run.step.started"})
+	if err != nil {
+		t.Fatalf("Append after reload error: %v", err)
+	}
+	if next.Sequence != 4 {
+		t.Fatalf("expected sequence to continue at 4, got %d", next.Sequence)
+	}
+
+	after, err := reloaded.List(ctx, "tnt_alpha", "run_1", 2)
+	if err != nil {
+		t.Fatalf("List after sequence error: %v", err)
+	}
+	if len(after) != 2 || after[0].Sequence != 3 {
+		t.Fatalf("expected events 3 and 4, got %+v", after)
+	}
+
+	if got, _ := reloaded.List(ctx, "tnt_other", "run_1", 0); len(got) != 0 {
+		t.Fatalf("expected tenant isolation on List, got %d events", len(got))
+	}
+
+	if _, err := store.Append(ctx, types.Event{RunID: "run_1", Type: "agentos.run.created"}); !errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("expected ErrInvalidEvent without tenant, got %v", err)
+	}
+}
+
+func TestFileEventStoreBoundsCachedLogs(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileEventStore(filepath.Join(t.TempDir(), "events"))
+	if err != nil {
+		t.Fatalf("NewFileEventStore error: %v", err)
+	}
+	fs := store.(*fileEventStore)
+	fs.maxLogs = 2
+
+	for _, runID := range []string{"run_1", "run_2", "run_1", "run_3"} {
+		if _, err := store.Append(ctx, types.Event{TenantID: "tnt_alpha", RunID: runID, Type: "agentos.run.created"}); err != nil {
+			t.Fatalf("Append %s error: %v", runID, err)
+		}
+	}
+	if len(fs.logs) != 2 || fs.logs[storageKey("tnt_alpha", "run_2")] != nil {
+		t.Fatalf("expected the least recently used log evicted, got %d cached", len(fs.logs))
+	}
+
+	events, err := store.List(ctx, "tnt_alpha", "run_2", 0)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("expected the evicted log read back from disk, got %d events, err %v", len(events), err)
+	}
+	next, err := store.Append(ctx, types.Event{TenantID: "tnt_alpha", RunID: "run_1", Type: "agentos.run.started"})
+	if err != nil || next.Sequence != 3 {
+		t.Fatalf("expected sequence 3 after reload, got %d, err %v", next.Sequence, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
@@ -100,6 +101,54 @@ func (s *fileRunStore) Save(ctx context.Context, run types.Run) error {
 	return s.persistLocked()
 }
 
+func (s *fileRunStore) List(ctx context.Context, tenantID string, filter RunFilter) ([]types.Run, string, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, "", err
+	}
+	if tenantID == "" {
+		return nil, "", nil
+	}
+
+	s.mu.Lock()
+	var runs []types.Run
+	for _, run := range s.runs {
+		if run.TenantID == tenantID && filter.Match(run) {
+			runs = append(runs, run)
+		}
+	}
+	s.mu.Unlock()
+
+	return paginateRuns(runs, filter)
+}
+
+func (s *fileRunStore) ListByStatus(ctx context.Context, statuses ...string) ([]types.Run, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	filter := RunFilter{Statuses: statuses}
+
+	s.mu.Lock()
+	var runs []types.Run
+	for _, run := range s.runs {
+		if filter.Match(run) {
+			runs = append(runs, run)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(runs, func(i, j int) bool {
+		ci, cj := runCreatedAt(runs[i]), runCreatedAt(runs[j])
+		if !ci.Equal(cj) {
+			return ci.Before(cj)
+		}
+		return runs[i].RunID < runs[j].RunID
+	})
+	return runs, nil
+}
+
 func (s *fileRunStore) load() error {
 	if s.path == "" {
 		return nil
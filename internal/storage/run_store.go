@@ -2,10 +2,14 @@ package storage
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
 )
@@ -15,6 +19,8 @@ var (
 	ErrRunExists = errors.New("run already exists")
 	// ErrInvalidRun signals missing required run identity fields.
 	ErrInvalidRun = errors.New("invalid run")
+	// ErrInvalidCursor signals a malformed pagination cursor.
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
 
 // RunStore is a tenant-scoped persistence port for run state.
@@ -23,11 +29,133 @@ type RunStore interface {
 	Get(ctx context.Context, tenantID, runID string) (types.Run, bool, error)
 	GetByIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string) (types.Run, bool, error)
 	Save(ctx context.Context, run types.Run) error
+	// List returns the tenant's runs matching filter, newest first, and a cursor for the
+	// next page (empty when there are no more results).
+	List(ctx context.Context, tenantID string, filter RunFilter) ([]types.Run, string, error)
+	// ListByStatus returns the runs of every tenant whose status is one of statuses,
+	// oldest first. It backs startup recovery, not tenant-facing queries.
+	ListByStatus(ctx context.Context, statuses ...string) ([]types.Run, error)
 }
 
-// NewRunStoreFromEnv constructs the default run store adapter, using AGENTOS_RUN_STORE_FILE
-// or falling back to ./data/agent-orchestrator/runs.json.
+// RunFilter narrows RunStore.List results. Zero values match everything.
+type RunFilter struct {
+	AgentID        string
+	Statuses       []string
+	CreatedAfter   time.Time // inclusive
+	CreatedBefore  time.Time // exclusive
+	IdempotencyKey string
+	Cursor         string // opaque value returned by a previous List call
+	Limit          int    // <= 0 means no limit
+}
+
+// Match reports whether run satisfies the filter (the cursor is not considered).
+func (f RunFilter) Match(run types.Run) bool {
+	if f.AgentID != "" && run.AgentID != f.AgentID {
+		return false
+	}
+	if f.IdempotencyKey != "" && run.IdempotencyKey != f.IdempotencyKey {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, st := range f.Statuses {
+			if run.Status == st {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.CreatedAfter.IsZero() || !f.CreatedBefore.IsZero() {
+		created := runCreatedAt(run)
+		if !f.CreatedAfter.IsZero() && created.Before(f.CreatedAfter) {
+			return false
+		}
+		if !f.CreatedBefore.IsZero() && !created.Before(f.CreatedBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+type runCursor struct {
+	CreatedAt string `json:"c"`
+	RunID     string `json:"r"`
+}
+
+// paginateRuns orders matching runs newest first (run_id breaks ties) and applies the
+// filter's cursor and limit.
+func paginateRuns(runs []types.Run, filter RunFilter) ([]types.Run, string, error) {
+	sort.Slice(runs, func(i, j int) bool {
+		ci, cj := runCreatedAt(runs[i]), runCreatedAt(runs[j])
+		if !ci.Equal(cj) {
+			return ci.After(cj)
+		}
+		return runs[i].RunID > runs[j].RunID
+	})
+
+	if filter.Cursor != "" {
+		cur, err := decodeRunCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		curAt, _ := time.Parse(time.RFC3339, cur.CreatedAt)
+		start := len(runs)
+		for i, run := range runs {
+			c := runCreatedAt(run)
+			if c.Before(curAt) || (c.Equal(curAt) && run.RunID < cur.RunID) {
+				start = i
+				break
+			}
+		}
+		runs = runs[start:]
+	}
+
+	if filter.Limit > 0 && len(runs) > filter.Limit {
+		last := runs[filter.Limit-1]
+		return runs[:filter.Limit], encodeRunCursor(last), nil
+	}
+	return runs, "", nil
+}
+
+func encodeRunCursor(run types.Run) string {
+	b, _ := json.Marshal(runCursor{CreatedAt: runCreatedAt(run).Format(time.RFC3339), RunID: run.RunID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeRunCursor(raw string) (runCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return runCursor{}, ErrInvalidCursor
+	}
+	var cur runCursor
+	if err := json.Unmarshal(b, &cur); err != nil || cur.RunID == "" {
+		return runCursor{}, ErrInvalidCursor
+	}
+	if _, err := time.Parse(time.RFC3339, cur.CreatedAt); err != nil {
+		return runCursor{}, ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// runCreatedAt parses CreatedAt; unparseable values sort as the zero time.
+func runCreatedAt(run types.Run) time.Time {
+	t, err := time.Parse(time.RFC3339, run.CreatedAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// NewRunStoreFromEnv constructs the default run store adapter. AGENTOS_RUN_STORE_DSN (e.g.
+// "sqlite:data/agent-orchestrator/agentos.db") selects the SQL adapter; otherwise runs are
+// kept in AGENTOS_RUN_STORE_FILE, falling back to ./data/agent-orchestrator/runs.json.
 func NewRunStoreFromEnv() (RunStore, error) {
+	if dsn := strings.TrimSpace(os.Getenv("AGENTOS_RUN_STORE_DSN")); dsn != "" {
+		return NewSQLRunStore(dsn)
+	}
 	path := strings.TrimSpace(os.Getenv("AGENTOS_RUN_STORE_FILE"))
 	if path == "" {
 		path = filepath.Join("data", "agent-orchestrator", "runs.json")
@@ -201,3 +201,87 @@ func TestDifferentTenantsCanUseSameIdempotencyKey(t *testing.T) {
 		t.Fatalf("expected not found for tenant that didn't create a run")
 	}
 }
+
+func TestFileRunStoreListFiltersAndPaginates(t *testing.T) {
+	store, err := NewFileRunStore(filepath.Join(t.TempDir(), "runs.json"))
+	if err != nil {
+		t.Fatalf("NewFileRunStore error: %v", err)
+	}
+	testRunStoreList(t, store)
+}
+
+// testRunStoreList checks List filtering, ordering and pagination against any RunStore.
+func testRunStoreList(t *testing.T, store RunStore) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := []types.Run{
+		{TenantID: "tnt_alpha", AgentID: "agt_a", RunID: "run_1", Status: "completed", CreatedAt: base.Format(time.RFC3339)},
+		{TenantID: "tnt_alpha", AgentID: "agt_a", RunID: "run_2", Status: "queued", CreatedAt: base.Add(time.Minute).Format(time.RFC3339), IdempotencyKey: "idem_2"},
+		{TenantID: "tnt_alpha", AgentID: "agt_b", RunID: "run_3", Status: "failed", CreatedAt: base.Add(2 * time.Minute).Format(time.RFC3339)},
+		{TenantID: "tnt_alpha", AgentID: "agt_a", RunID: "run_4", Status: "completed", CreatedAt: base.Add(2 * time.Minute).Format(time.RFC3339)},
+		{TenantID: "tnt_beta", AgentID: "agt_a", RunID: "run_5", Status: "completed", CreatedAt: base.Format(time.RFC3339)},
+	}
+	for _, run := range runs {
+		if err := store.Create(ctx, run); err != nil {
+			t.Fatalf("Create %s error: %v", run.RunID, err)
+		}
+	}
+
+	ids := func(runs []types.Run) []string {
+		out := make([]string, 0, len(runs))
+		for _, r := range runs {
+			out = append(out, r.RunID)
+		}
+		return out
+	}
+
+	all, next, err := store.List(ctx, "tnt_alpha", RunFilter{})
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if got := ids(all); len(got) != 4 || got[0] != "run_4" || got[1] != "run_3" || got[3] != "run_1" || next != "" {
+		t.Fatalf("expected newest-first tenant runs without cursor, got %v next=%q", got, next)
+	}
+
+	page1, next, err := store.List(ctx, "tnt_alpha", RunFilter{Limit: 3})
+	if err != nil || next == "" {
+		t.Fatalf("expected next cursor, got %q err=%v", next, err)
+	}
+	page2, next2, err := store.List(ctx, "tnt_alpha", RunFilter{Limit: 3, Cursor: next})
+	if err != nil {
+		t.Fatalf("List page 2 error: %v", err)
+	}
+	if len(page1) != 3 || len(page2) != 1 || page2[0].RunID != "run_1" || next2 != "" {
+		t.Fatalf("unexpected pagination page1=%v page2=%v next=%q", ids(page1), ids(page2), next2)
+	}
+
+	filtered, _, err := store.List(ctx, "tnt_alpha", RunFilter{
+		AgentID:      "agt_a",
+		Statuses:     []string{"completed", "queued"},
+		CreatedAfter: base.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("List filtered error: %v", err)
+	}
+	if got := ids(filtered); len(got) != 2 || got[0] != "run_4" || got[1] != "run_2" {
+		t.Fatalf("unexpected filtered runs %v", got)
+	}
+
+	byKey, _, err := store.List(ctx, "tnt_alpha", RunFilter{IdempotencyKey: "idem_2", CreatedBefore: base.Add(2 * time.Minute)})
+	if err != nil || len(byKey) != 1 || byKey[0].RunID != "run_2" {
+		t.Fatalf("expected run_2 by idempotency key, got %v err=%v", ids(byKey), err)
+	}
+
+	if _, _, err := store.List(ctx, "tnt_alpha", RunFilter{Cursor: "not-a-cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	byStatus, err := store.ListByStatus(ctx, "queued", "completed")
+	if err != nil {
+		t.Fatalf("ListByStatus error: %v", err)
+	}
+	if got := ids(byStatus); len(got) != 4 || got[0] != "run_1" || got[1] != "run_5" || got[2] != "run_2" || got[3] != "run_4" {
+		t.Fatalf("expected oldest-first runs of all tenants, got %v", got)
+	}
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// ErrInvalidSession signals missing required session identity fields.
+var ErrInvalidSession = errors.New("invalid session")
+
+// SessionStore is a tenant-scoped persistence port for conversation sessions.
+type SessionStore interface {
+	Get(ctx context.Context, tenantID, sessionID string) (types.Session, bool, error)
+	// List returns the tenant's sessions, most recently updated first.
+	List(ctx context.Context, tenantID string) ([]types.Session, error)
+	Save(ctx context.Context, session types.Session) error
+	// Delete removes a session and reports whether it existed.
+	Delete(ctx context.Context, tenantID, sessionID string) (bool, error)
+}
+
+// fileSessionStore keeps one JSON file per session under {dir}/{tenant_id}/.
+type fileSessionStore struct {
+	mu       sync.Mutex
+	dir      string
+	sessions map[string]types.Session // key: tenant/{tenant_id}/sessions/{session_id}
+}
+
+// NewSessionStoreFromEnv constructs the default session store adapter.
+// AGENTOS_SESSION_STORE_DSN selects the SQL adapter (it may share the run store database);
+// otherwise sessions are kept as files under AGENTOS_SESSION_STORE_DIR.
+func NewSessionStoreFromEnv() (SessionStore, error) {
+	if dsn := strings.TrimSpace(os.Getenv("AGENTOS_SESSION_STORE_DSN")); dsn != "" {
+		return NewSQLSessionStore(dsn)
+	}
+	dir := strings.TrimSpace(os.Getenv("AGENTOS_SESSION_STORE_DIR"))
+	if dir == "" {
+		dir = filepath.Join("data", "agent-orchestrator", "sessions")
+	}
+	return NewFileSessionStore(dir)
+}
+
+// NewFileSessionStore returns a file-backed SessionStore.
+func NewFileSessionStore(dir string) (SessionStore, error) {
+	s := &fileSessionStore{
+		dir:      dir,
+		sessions: make(map[string]types.Session),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSessionStore) Get(ctx context.Context, tenantID, sessionID string) (types.Session, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Session{}, false, err
+	}
+	if tenantID == "" || sessionID == "" {
+		return types.Session{}, false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionStorageKey(tenantID, sessionID)]
+	return session, ok, nil
+}
+
+func (s *fileSessionStore) List(ctx context.Context, tenantID string) ([]types.Session, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sessions []types.Session
+	for _, session := range s.sessions {
+		if session.TenantID == tenantID {
+			sessions = append(sessions, session)
+		}
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (s *fileSessionStore) Save(ctx context.Context, session types.Session) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateSession(session); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionStorageKey(session.TenantID, session.SessionID)] = session
+	return s.persist(session)
+}
+
+func (s *fileSessionStore) Delete(ctx context.Context, tenantID, sessionID string) (bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return false, err
+	}
+	if tenantID == "" || sessionID == "" {
+		return false, nil
+	}
+	key := sessionStorageKey(tenantID, sessionID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[key]; !ok {
+		return false, nil
+	}
+	delete(s.sessions, key)
+	if s.dir == "" {
+		return true, nil
+	}
+	if err := os.Remove(filepath.Join(s.dir, tenantID, sessionID+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return true, err
+	}
+	return true, nil
+}
+
+func (s *fileSessionStore) load() error {
+	if s.dir == "" {
+		return nil
+	}
+
+	// Walk directory structure: {dir}/{tenant_id}/{session_id}.json
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			return nil
+		}
+		var session types.Session
+		if err := json.Unmarshal(b, &session); err != nil {
+			return err
+		}
+		if session.TenantID != "" && session.SessionID != "" {
+			s.sessions[sessionStorageKey(session.TenantID, session.SessionID)] = session
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *fileSessionStore) persist(session types.Session) error {
+	if s.dir == "" {
+		return nil
+	}
+	tenantDir := filepath.Join(s.dir, session.TenantID)
+	if err := os.MkdirAll(tenantDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(tenantDir, session.SessionID+".json")
+	b, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func validateSession(session types.Session) error {
+	if session.TenantID == "" || session.SessionID == "" || strings.ContainsAny(session.SessionID, `/\`) {
+		return ErrInvalidSession
+	}
+	return nil
+}
+
+func sortSessions(sessions []types.Session) {
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].UpdatedAt != sessions[j].UpdatedAt {
+			return sessions[i].UpdatedAt > sessions[j].UpdatedAt
+		}
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+}
+
+func sessionStorageKey(tenantID, sessionID string) string {
+	return filepath.ToSlash(filepath.Join("tenant", tenantID, "sessions", sessionID))
+}
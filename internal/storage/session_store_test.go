@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestFileSessionStorePersistsAndDeletes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	store, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileSessionStore error: %v", err)
+	}
+	testSessionStore(t, store)
+
+	reloaded, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatalf("reload store error: %v", err)
+	}
+	sessions, err := reloaded.List(context.Background(), "tnt_alpha")
+	if err != nil || len(sessions) != 1 || sessions[0].SessionID != "ses_b" || len(sessions[0].Turns) != 2 {
+		t.Fatalf("expected the remaining session after reload, got %+v err=%v", sessions, err)
+	}
+}
+
+// testSessionStore checks ordering, tenant scoping and deletion against any SessionStore.
+func testSessionStore(t *testing.T, store SessionStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := store.Save(ctx, types.Session{TenantID: "tnt_alpha"}); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected ErrInvalidSession, got %v", err)
+	}
+	turns := []types.SessionTurn{
+		{RunID: "run_1", Role: "user", Text: "hi", Tokens: 1},
+		{RunID: "run_1", Role: "assistant", Text: "hello", Tokens: 2},
+	}
+	for _, session := range []types.Session{
+		{TenantID: "tnt_alpha", SessionID: "ses_a", CreatedAt: "2026-01-01T00:00:00Z", UpdatedAt: "2026-01-01T00:00:00Z", Turns: turns},
+		{TenantID: "tnt_alpha", SessionID: "ses_b", CreatedAt: "2026-01-01T00:00:00Z", UpdatedAt: "2026-01-02T00:00:00Z", Turns: turns},
+		{TenantID: "tnt_beta", SessionID: "ses_a", CreatedAt: "2026-01-01T00:00:00Z", UpdatedAt: "2026-01-03T00:00:00Z"},
+	} {
+		if err := store.Save(ctx, session); err != nil {
+			t.Fatalf("Save session error: %v", err)
+		}
+	}
+
+	sessions, err := store.List(ctx, "tnt_alpha")
+	if err != nil || len(sessions) != 2 || sessions[0].SessionID != "ses_b" {
+		t.Fatalf("expected tenant sessions most recent first, got %+v err=%v", sessions, err)
+	}
+	got, ok, err := store.Get(ctx, "tnt_alpha", "ses_a")
+	if err != nil || !ok || len(got.Turns) != 2 || got.Turns[1].Text != "hello" {
+		t.Fatalf("expected session with turns, got %+v ok=%v err=%v", got, ok, err)
+	}
+
+	if deleted, err := store.Delete(ctx, "tnt_alpha", "ses_a"); err != nil || !deleted {
+		t.Fatalf("expected delete to succeed, got %v err=%v", deleted, err)
+	}
+	if deleted, _ := store.Delete(ctx, "tnt_alpha", "ses_a"); deleted {
+		t.Fatalf("expected second delete to report missing session")
+	}
+	if _, ok, _ := store.Get(ctx, "tnt_beta", "ses_a"); !ok {
+		t.Fatalf("expected delete to stay tenant scoped")
+	}
+}
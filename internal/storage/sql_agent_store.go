@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// sqlAgentStore persists agents in an embedded SQL database.
+type sqlAgentStore struct {
+	db *sql.DB
+}
+
+// NewSQLAgentStore returns an SQL-backed AgentStore for dsn (e.g. "sqlite:path/to/agentos.db"),
+// applying pending schema migrations.
+func NewSQLAgentStore(dsn string) (AgentStore, error) {
+	db, err := openSQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlAgentStore{db: db}, nil
+}
+
+func (s *sqlAgentStore) Create(ctx context.Context, agent types.Agent) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateAgent(agent); err != nil {
+		return err
+	}
+	b, err := json.Marshal(agent)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO agents (tenant_id, agent_id, status, created_at, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, agent_id) DO NOTHING`,
+		agent.TenantID, agent.AgentID, agent.Status, agent.CreatedAt, string(b))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAgentExists
+	}
+	return nil
+}
+
+func (s *sqlAgentStore) Get(ctx context.Context, tenantID, agentID string) (types.Agent, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Agent{}, false, err
+	}
+	if tenantID == "" || agentID == "" {
+		return types.Agent{}, false, nil
+	}
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM agents WHERE tenant_id = ? AND agent_id = ?`, tenantID, agentID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Agent{}, false, nil
+	}
+	if err != nil {
+		return types.Agent{}, false, err
+	}
+	var agent types.Agent
+	if err := json.Unmarshal([]byte(data), &agent); err != nil {
+		return types.Agent{}, false, err
+	}
+	return agent, true, nil
+}
+
+func (s *sqlAgentStore) List(ctx context.Context, tenantID string) ([]types.Agent, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM agents WHERE tenant_id = ? ORDER BY created_at, agent_id`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []types.Agent
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var agent types.Agent
+		if err := json.Unmarshal([]byte(data), &agent); err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+	return agents, rows.Err()
+}
+
+func (s *sqlAgentStore) Save(ctx context.Context, agent types.Agent) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateAgent(agent); err != nil {
+		return err
+	}
+	b, err := json.Marshal(agent)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO agents (tenant_id, agent_id, status, created_at, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, agent_id) DO UPDATE SET
+			status = excluded.status,
+			created_at = excluded.created_at,
+			data = excluded.data`,
+		agent.TenantID, agent.AgentID, agent.Status, agent.CreatedAt, string(b))
+	return err
+}
+
+func (s *sqlAgentStore) CreateVersion(ctx context.Context, version types.AgentVersion) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if version.TenantID == "" || version.AgentID == "" || version.Version == "" {
+		return ErrInvalidAgent
+	}
+	b, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO agent_versions (tenant_id, agent_id, version, created_at, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, agent_id, version) DO NOTHING`,
+		version.TenantID, version.AgentID, version.Version, version.CreatedAt, string(b))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAgentVersionExists
+	}
+	return nil
+}
+
+func (s *sqlAgentStore) GetVersion(ctx context.Context, tenantID, agentID, version string) (types.AgentVersion, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.AgentVersion{}, false, err
+	}
+	if tenantID == "" || agentID == "" || version == "" {
+		return types.AgentVersion{}, false, nil
+	}
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM agent_versions WHERE tenant_id = ? AND agent_id = ? AND version = ?`,
+		tenantID, agentID, version).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.AgentVersion{}, false, nil
+	}
+	if err != nil {
+		return types.AgentVersion{}, false, err
+	}
+	var v types.AgentVersion
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return types.AgentVersion{}, false, err
+	}
+	return v, true, nil
+}
+
+func (s *sqlAgentStore) ListVersions(ctx context.Context, tenantID, agentID string) ([]types.AgentVersion, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" || agentID == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM agent_versions WHERE tenant_id = ? AND agent_id = ?`, tenantID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []types.AgentVersion
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var v types.AgentVersion
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortAgentVersions(versions)
+	return versions, nil
+}
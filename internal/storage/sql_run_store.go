@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// sqlRunStore persists runs in an embedded SQL database. Unlike fileRunStore it writes
+// single rows and resolves idempotency keys and status filters through indexes.
+type sqlRunStore struct {
+	db *sql.DB
+}
+
+// NewSQLRunStore returns an SQL-backed RunStore for dsn (e.g. "sqlite:path/to/agentos.db"),
+// applying pending schema migrations.
+func NewSQLRunStore(dsn string) (RunStore, error) {
+	db, err := openSQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRunStore{db: db}, nil
+}
+
+func (s *sqlRunStore) Create(ctx context.Context, run types.Run) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateRun(run); err != nil {
+		return err
+	}
+	b, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO runs (tenant_id, run_id, agent_id, status, idempotency_key, created_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, run_id) DO NOTHING`,
+		run.TenantID, run.RunID, run.AgentID, run.Status, run.IdempotencyKey, sqlRunCreatedAt(run), string(b))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrRunExists
+	}
+	return nil
+}
+
+func (s *sqlRunStore) Get(ctx context.Context, tenantID, runID string) (types.Run, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Run{}, false, err
+	}
+	if tenantID == "" || runID == "" {
+		return types.Run{}, false, nil
+	}
+	row := s.db.QueryRowContext(ctx, `SELECT data FROM runs WHERE tenant_id = ? AND run_id = ?`, tenantID, runID)
+	return scanRun(row)
+}
+
+func (s *sqlRunStore) GetByIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string) (types.Run, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Run{}, false, err
+	}
+	if tenantID == "" || idempotencyKey == "" {
+		return types.Run{}, false, nil
+	}
+	row := s.db.QueryRowContext(ctx, `
+		SELECT data FROM runs
+		WHERE tenant_id = ? AND idempotency_key = ? AND idempotency_key <> ''
+		ORDER BY created_at, run_id LIMIT 1`, tenantID, idempotencyKey)
+	return scanRun(row)
+}
+
+func (s *sqlRunStore) Save(ctx context.Context, run types.Run) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateRun(run); err != nil {
+		return err
+	}
+	b, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO runs (tenant_id, run_id, agent_id, status, idempotency_key, created_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, run_id) DO UPDATE SET
+			agent_id = excluded.agent_id,
+			status = excluded.status,
+			idempotency_key = excluded.idempotency_key,
+			created_at = excluded.created_at,
+			data = excluded.data`,
+		run.TenantID, run.RunID, run.AgentID, run.Status, run.IdempotencyKey, sqlRunCreatedAt(run), string(b))
+	return err
+}
+
+func (s *sqlRunStore) List(ctx context.Context, tenantID string, filter RunFilter) ([]types.Run, string, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, "", err
+	}
+	if tenantID == "" {
+		return nil, "", nil
+	}
+
+	where := []string{"tenant_id = ?"}
+	args := []any{tenantID}
+	if filter.AgentID != "" {
+		where = append(where, "agent_id = ?")
+		args = append(args, filter.AgentID)
+	}
+	if filter.IdempotencyKey != "" {
+		where = append(where, "idempotency_key = ?")
+		args = append(args, filter.IdempotencyKey)
+	}
+	if len(filter.Statuses) > 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(filter.Statuses)-1)+")")
+		for _, st := range filter.Statuses {
+			args = append(args, st)
+		}
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UTC().Format(time.RFC3339))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UTC().Format(time.RFC3339))
+	}
+	if filter.Cursor != "" {
+		cur, err := decodeRunCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		where = append(where, "(created_at < ? OR (created_at = ? AND run_id < ?))")
+		args = append(args, cur.CreatedAt, cur.CreatedAt, cur.RunID)
+	}
+
+	query := "SELECT data FROM runs WHERE " + strings.Join(where, " AND ") + " ORDER BY created_at DESC, run_id DESC"
+	if filter.Limit > 0 {
+		// fetch one extra row to know whether another page exists
+		query += " LIMIT ?"
+		args = append(args, filter.Limit+1)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var runs []types.Run
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, "", err
+		}
+		var run types.Run
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, "", err
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if filter.Limit > 0 && len(runs) > filter.Limit {
+		runs = runs[:filter.Limit]
+		return runs, encodeRunCursor(runs[len(runs)-1]), nil
+	}
+	return runs, "", nil
+}
+
+func (s *sqlRunStore) ListByStatus(ctx context.Context, statuses ...string) ([]types.Run, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(statuses))
+	for _, st := range statuses {
+		args = append(args, st)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT data FROM runs WHERE status IN (?"+strings.Repeat(", ?", len(statuses)-1)+") ORDER BY created_at, run_id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []types.Run
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var run types.Run
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func scanRun(row *sql.Row) (types.Run, bool, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Run{}, false, nil
+		}
+		return types.Run{}, false, err
+	}
+	var run types.Run
+	if err := json.Unmarshal([]byte(data), &run); err != nil {
+		return types.Run{}, false, err
+	}
+	return run, true, nil
+}
+
+// sqlRunCreatedAt normalizes CreatedAt to UTC RFC3339 so that text ordering in SQL matches
+// the ordering used by paginateRuns and the cursor encoding.
+func sqlRunCreatedAt(run types.Run) string {
+	return runCreatedAt(run).Format(time.RFC3339)
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// sqlSessionStore persists sessions in an embedded SQL database.
+type sqlSessionStore struct {
+	db *sql.DB
+}
+
+// NewSQLSessionStore returns an SQL-backed SessionStore for dsn (e.g.
+// "sqlite:path/to/agentos.db"), applying pending schema migrations.
+func NewSQLSessionStore(dsn string) (SessionStore, error) {
+	db, err := openSQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlSessionStore{db: db}, nil
+}
+
+func (s *sqlSessionStore) Get(ctx context.Context, tenantID, sessionID string) (types.Session, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Session{}, false, err
+	}
+	if tenantID == "" || sessionID == "" {
+		return types.Session{}, false, nil
+	}
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM sessions WHERE tenant_id = ? AND session_id = ?`, tenantID, sessionID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Session{}, false, nil
+	}
+	if err != nil {
+		return types.Session{}, false, err
+	}
+	var session types.Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return types.Session{}, false, err
+	}
+	return session, true, nil
+}
+
+func (s *sqlSessionStore) List(ctx context.Context, tenantID string) ([]types.Session, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM sessions WHERE tenant_id = ? ORDER BY updated_at DESC, session_id`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []types.Session
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var session types.Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *sqlSessionStore) Save(ctx context.Context, session types.Session) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateSession(session); err != nil {
+		return err
+	}
+	b, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO sessions (tenant_id, session_id, updated_at, data)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (tenant_id, session_id) DO UPDATE SET
+			updated_at = excluded.updated_at,
+			data = excluded.data`,
+		session.TenantID, session.SessionID, session.UpdatedAt, string(b))
+	return err
+}
+
+func (s *sqlSessionStore) Delete(ctx context.Context, tenantID, sessionID string) (bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return false, err
+	}
+	if tenantID == "" || sessionID == "" {
+		return false, nil
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE tenant_id = ? AND session_id = ?`, tenantID, sessionID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// SQLite driver for the embedded SQL adapters; requires cgo.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteScheme prefixes store DSNs that select the embedded SQLite adapters, e.g.
+// "sqlite:data/agent-orchestrator/agentos.db".
+const sqliteScheme = "sqlite:"
+
+// migrations are applied in order and recorded in schema_migrations; append new
+// statements, never edit applied ones. Rows keep the full record as JSON in data and
+// copy the columns that are filtered or indexed on.
+var migrations = []string{
+	// 1: runs
+	`CREATE TABLE IF NOT EXISTS runs (
+		tenant_id       TEXT NOT NULL,
+		run_id          TEXT NOT NULL,
+		agent_id        TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		idempotency_key TEXT NOT NULL DEFAULT '',
+		created_at      TEXT NOT NULL,
+		data            TEXT NOT NULL,
+		PRIMARY KEY (tenant_id, run_id)
+	);
+	CREATE INDEX IF NOT EXISTS runs_tenant_idempotency_key ON runs (tenant_id, idempotency_key) WHERE idempotency_key <> '';
+	CREATE INDEX IF NOT EXISTS runs_tenant_status ON runs (tenant_id, status, created_at);
+	CREATE INDEX IF NOT EXISTS runs_tenant_created ON runs (tenant_id, created_at, run_id);`,
+	// 2: agents
+	`CREATE TABLE IF NOT EXISTS agents (
+		tenant_id  TEXT NOT NULL,
+		agent_id   TEXT NOT NULL,
+		status     TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		PRIMARY KEY (tenant_id, agent_id)
+	);`,
+	// 3: agent versions
+	`CREATE TABLE IF NOT EXISTS agent_versions (
+		tenant_id  TEXT NOT NULL,
+		agent_id   TEXT NOT NULL,
+		version    TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		PRIMARY KEY (tenant_id, agent_id, version)
+	);`,
+	// 4: sessions
+	`CREATE TABLE IF NOT EXISTS sessions (
+		tenant_id  TEXT NOT NULL,
+		session_id TEXT NOT NULL,
+		updated_at TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		PRIMARY KEY (tenant_id, session_id)
+	);
+	CREATE INDEX IF NOT EXISTS sessions_tenant_updated ON sessions (tenant_id, updated_at);`,
+	// 5: webhooks and their delivery log
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		tenant_id  TEXT NOT NULL,
+		webhook_id TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		PRIMARY KEY (tenant_id, webhook_id)
+	);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		tenant_id   TEXT NOT NULL,
+		delivery_id TEXT NOT NULL,
+		webhook_id  TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		created_at  TEXT NOT NULL,
+		data        TEXT NOT NULL,
+		PRIMARY KEY (tenant_id, delivery_id)
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_tenant_created ON webhook_deliveries (tenant_id, created_at);`,
+	// 6: tenants and the revision counter bumped by every tenant write
+	`CREATE TABLE IF NOT EXISTS tenants (
+		tenant_id  TEXT NOT NULL PRIMARY KEY,
+		updated_at TEXT NOT NULL,
+		data       TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS tenant_revision (
+		id       INTEGER NOT NULL PRIMARY KEY CHECK (id = 1),
+		revision INTEGER NOT NULL
+	);
+	INSERT OR IGNORE INTO tenant_revision (id, revision) VALUES (1, 0);`,
+	// 7: pending webhook deliveries resumed on startup, finished ones pruned by age
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_status_created ON webhook_deliveries (status, created_at);`,
+}
+
+// isSQLDSN reports whether a store setting selects an SQL adapter.
+func isSQLDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, sqliteScheme)
+}
+
+// openSQL opens the database named by dsn and brings its schema up to date.
+func openSQL(dsn string) (*sql.DB, error) {
+	if !isSQLDSN(dsn) {
+		return nil, fmt.Errorf("unsupported store dsn %q", dsn)
+	}
+	path := strings.TrimPrefix(dsn, sqliteScheme)
+	if path == "" {
+		return nil, fmt.Errorf("store dsn %q has no database path", dsn)
+	}
+	if file, _, _ := strings.Cut(path, "?"); file != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+sep+"_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serializes writers anyway; a single connection avoids SQLITE_BUSY between
+	// connections of the same process and keeps ":memory:" databases shared.
+	db.SetMaxOpenConns(1)
+	if err := migrate(context.Background(), db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for i := current; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, i+1); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
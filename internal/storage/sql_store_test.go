@@ -0,0 +1,169 @@
+//go:build cgo
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestSQLRunStorePersistsRunsAndEnforcesTenantScope(t *testing.T) {
+	ctx := context.Background()
+	dsn := "sqlite:" + filepath.Join(t.TempDir(), "agentos.db")
+
+	store, err := NewSQLRunStore(dsn)
+	if err != nil {
+		t.Fatalf("NewSQLRunStore error: %v", err)
+	}
+
+	run := types.Run{
+		TenantID:       "tnt_alpha",
+		AgentID:        "agt_demo",
+		RunID:          "run_123",
+		Status:         "queued",
+		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+		EventsURL:      "/v1/runs/run_123/events",
+		Input:          &types.RunInput{Type: "text", Text: "hello"},
+		IdempotencyKey: "idem_shared",
+	}
+	if err := store.Create(ctx, run); err != nil {
+		t.Fatalf("Create run error: %v", err)
+	}
+	if err := store.Create(ctx, run); !errors.Is(err, ErrRunExists) {
+		t.Fatalf("expected ErrRunExists on duplicate create, got %v", err)
+	}
+
+	other := run
+	other.TenantID = "tnt_beta"
+	other.RunID = "run_456"
+	if err := store.Create(ctx, other); err != nil {
+		t.Fatalf("Create run for other tenant error: %v", err)
+	}
+
+	if _, ok, _ := store.Get(ctx, "tnt_beta", run.RunID); ok {
+		t.Fatalf("expected tenant isolation on Get")
+	}
+	found, ok, err := store.GetByIdempotencyKey(ctx, "tnt_alpha", "idem_shared")
+	if err != nil || !ok || found.RunID != run.RunID {
+		t.Fatalf("expected run_123 by idempotency key, got %+v ok=%v err=%v", found, ok, err)
+	}
+	found, ok, err = store.GetByIdempotencyKey(ctx, "tnt_beta", "idem_shared")
+	if err != nil || !ok || found.RunID != other.RunID {
+		t.Fatalf("expected run_456 for tenant beta, got %+v ok=%v err=%v", found, ok, err)
+	}
+	if _, ok, _ := store.GetByIdempotencyKey(ctx, "tnt_gamma", "idem_shared"); ok {
+		t.Fatalf("expected tenant isolation on GetByIdempotencyKey")
+	}
+
+	found.Status = "completed"
+	found.Output = &types.RunOutput{Type: "text", Text: "done"}
+	if err := store.Save(ctx, found); err != nil {
+		t.Fatalf("Save run error: %v", err)
+	}
+
+	reloaded, err := NewSQLRunStore(dsn)
+	if err != nil {
+		t.Fatalf("reopen store error: %v", err)
+	}
+	persisted, ok, err := reloaded.Get(ctx, "tnt_beta", other.RunID)
+	if err != nil || !ok {
+		t.Fatalf("run missing after reopen: ok=%v err=%v", ok, err)
+	}
+	if persisted.Status != "completed" || persisted.Output == nil || persisted.Output.Text != "done" {
+		t.Fatalf("expected persisted completion, got %+v", persisted)
+	}
+	completed, _, err := reloaded.List(ctx, "tnt_alpha", RunFilter{Statuses: []string{"completed"}})
+	if err != nil || len(completed) != 0 {
+		t.Fatalf("expected status filter to stay tenant scoped, got %d runs err=%v", len(completed), err)
+	}
+}
+
+func TestSQLRunStoreListFiltersAndPaginates(t *testing.T) {
+	store, err := NewSQLRunStore("sqlite:" + filepath.Join(t.TempDir(), "agentos.db"))
+	if err != nil {
+		t.Fatalf("NewSQLRunStore error: %v", err)
+	}
+	testRunStoreList(t, store)
+}
+
+func TestSQLAgentStoreEnforcesTenantScope(t *testing.T) {
+	ctx := context.Background()
+	// runs and agents share one database; each store applies the migrations it finds pending
+	dsn := "sqlite:" + filepath.Join(t.TempDir(), "agentos.db")
+	if _, err := NewSQLRunStore(dsn); err != nil {
+		t.Fatalf("NewSQLRunStore error: %v", err)
+	}
+	store, err := NewSQLAgentStore(dsn)
+	if err != nil {
+		t.Fatalf("NewSQLAgentStore error: %v", err)
+	}
+
+	agent := types.Agent{TenantID: "tnt_alpha", AgentID: "agt_demo", Name: "Demo", Status: "active", CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	if err := store.Create(ctx, agent); err != nil {
+		t.Fatalf("Create agent error: %v", err)
+	}
+	if err := store.Create(ctx, agent); !errors.Is(err, ErrAgentExists) {
+		t.Fatalf("expected ErrAgentExists, got %v", err)
+	}
+	if err := store.Create(ctx, types.Agent{TenantID: "tnt_alpha"}); !errors.Is(err, ErrInvalidAgent) {
+		t.Fatalf("expected ErrInvalidAgent, got %v", err)
+	}
+
+	if _, ok, _ := store.Get(ctx, "tnt_beta", agent.AgentID); ok {
+		t.Fatalf("expected tenant isolation on Get")
+	}
+	if agents, _ := store.List(ctx, "tnt_beta"); len(agents) != 0 {
+		t.Fatalf("expected tenant isolation on List, got %d agents", len(agents))
+	}
+
+	agent.Status = "deprecated"
+	if err := store.Save(ctx, agent); err != nil {
+		t.Fatalf("Save agent error: %v", err)
+	}
+	got, ok, err := store.Get(ctx, "tnt_alpha", agent.AgentID)
+	if err != nil || !ok || got.Status != "deprecated" || got.Name != "Demo" {
+		t.Fatalf("expected saved agent, got %+v ok=%v err=%v", got, ok, err)
+	}
+}
+
+func TestSQLAgentStoreVersionsAreImmutable(t *testing.T) {
+	store, err := NewSQLAgentStore("sqlite:" + filepath.Join(t.TempDir(), "agentos.db"))
+	if err != nil {
+		t.Fatalf("NewSQLAgentStore error: %v", err)
+	}
+	testAgentStoreVersions(t, store)
+}
+
+func TestSQLSessionStorePersistsAndDeletes(t *testing.T) {
+	store, err := NewSQLSessionStore("sqlite:" + filepath.Join(t.TempDir(), "agentos.db"))
+	if err != nil {
+		t.Fatalf("NewSQLSessionStore error: %v", err)
+	}
+	testSessionStore(t, store)
+}
+
+func TestSQLWebhookStorePersistsWebhooksAndDeliveries(t *testing.T) {
+	store, err := NewSQLWebhookStore("sqlite:" + filepath.Join(t.TempDir(), "agentos.db"))
+	if err != nil {
+		t.Fatalf("NewSQLWebhookStore error: %v", err)
+	}
+	testWebhookStore(t, store)
+	testWebhookDeliveryRetention(t, store)
+}
+
+func TestSQLTenantStoreSharesTenantsAcrossInstances(t *testing.T) {
+	dsn := "sqlite:" + filepath.Join(t.TempDir(), "agentos.db")
+	newStore := func() TenantStore {
+		store, err := NewSQLTenantStore(dsn)
+		if err != nil {
+			t.Fatalf("NewSQLTenantStore error: %v", err)
+		}
+		return store
+	}
+	testTenantStore(t, newStore(), newStore())
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// sqlTenantStore persists tenants in an embedded SQL database. Every write bumps the
+// tenant_revision row in the same transaction, which Revision reports.
+type sqlTenantStore struct {
+	db *sql.DB
+}
+
+// NewSQLTenantStore returns an SQL-backed TenantStore for dsn (e.g.
+// "sqlite:path/to/agentos.db"), applying pending schema migrations.
+func NewSQLTenantStore(dsn string) (TenantStore, error) {
+	db, err := openSQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTenantStore{db: db}, nil
+}
+
+func (s *sqlTenantStore) Get(ctx context.Context, tenantID string) (types.Tenant, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Tenant{}, false, err
+	}
+	if tenantID == "" {
+		return types.Tenant{}, false, nil
+	}
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM tenants WHERE tenant_id = ?`, tenantID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Tenant{}, false, nil
+	}
+	if err != nil {
+		return types.Tenant{}, false, err
+	}
+	var tenant types.Tenant
+	if err := json.Unmarshal([]byte(data), &tenant); err != nil {
+		return types.Tenant{}, false, err
+	}
+	return tenant, true, nil
+}
+
+func (s *sqlTenantStore) List(ctx context.Context) ([]types.Tenant, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM tenants ORDER BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []types.Tenant{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var tenant types.Tenant
+		if err := json.Unmarshal([]byte(data), &tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+func (s *sqlTenantStore) Create(ctx context.Context, tenant types.Tenant) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if tenant.TenantID == "" {
+		return ErrInvalidTenant
+	}
+	b, err := json.Marshal(tenant)
+	if err != nil {
+		return err
+	}
+	return s.write(ctx, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `INSERT INTO tenants (tenant_id, updated_at, data) VALUES (?, ?, ?) ON CONFLICT (tenant_id) DO NOTHING`,
+			tenant.TenantID, tenant.UpdatedAt, string(b))
+		if err != nil {
+			return false, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return false, err
+		} else if n == 0 {
+			return false, ErrTenantExists
+		}
+		return true, nil
+	})
+}
+
+func (s *sqlTenantStore) Save(ctx context.Context, tenant types.Tenant) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if tenant.TenantID == "" {
+		return ErrInvalidTenant
+	}
+	b, err := json.Marshal(tenant)
+	if err != nil {
+		return err
+	}
+	return s.write(ctx, func(tx *sql.Tx) (bool, error) {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO tenants (tenant_id, updated_at, data)
+			VALUES (?, ?, ?)
+			ON CONFLICT (tenant_id) DO UPDATE SET
+				updated_at = excluded.updated_at,
+				data = excluded.data`,
+			tenant.TenantID, tenant.UpdatedAt, string(b))
+		return err == nil, err
+	})
+}
+
+func (s *sqlTenantStore) Delete(ctx context.Context, tenantID string) (bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return false, err
+	}
+	if tenantID == "" {
+		return false, nil
+	}
+	deleted := false
+	err := s.write(ctx, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `DELETE FROM tenants WHERE tenant_id = ?`, tenantID)
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		deleted = n > 0
+		return deleted, err
+	})
+	return deleted && err == nil, err
+}
+
+func (s *sqlTenantStore) Revision(ctx context.Context) (string, error) {
+	if err := ctxErr(ctx); err != nil {
+		return "", err
+	}
+	var revision int64
+	if err := s.db.QueryRowContext(ctx, `SELECT revision FROM tenant_revision WHERE id = 1`).Scan(&revision); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(revision, 10), nil
+}
+
+// write runs fn in a transaction and bumps the revision when fn reports a change.
+func (s *sqlTenantStore) write(ctx context.Context, fn func(tx *sql.Tx) (bool, error)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	changed, err := fn(tx)
+	if err == nil && changed {
+		_, err = tx.ExecContext(ctx, `UPDATE tenant_revision SET revision = revision + 1 WHERE id = 1`)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
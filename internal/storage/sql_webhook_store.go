@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// sqlWebhookStore persists webhooks and their delivery log in an embedded SQL database.
+type sqlWebhookStore struct {
+	db *sql.DB
+}
+
+// NewSQLWebhookStore returns an SQL-backed WebhookStore for dsn (e.g.
+// "sqlite:path/to/agentos.db"), applying pending schema migrations.
+func NewSQLWebhookStore(dsn string) (WebhookStore, error) {
+	db, err := openSQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlWebhookStore{db: db}, nil
+}
+
+func (s *sqlWebhookStore) Get(ctx context.Context, tenantID, webhookID string) (types.Webhook, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Webhook{}, false, err
+	}
+	if tenantID == "" || webhookID == "" {
+		return types.Webhook{}, false, nil
+	}
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM webhooks WHERE tenant_id = ? AND webhook_id = ?`, tenantID, webhookID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Webhook{}, false, nil
+	}
+	if err != nil {
+		return types.Webhook{}, false, err
+	}
+	var webhook types.Webhook
+	if err := json.Unmarshal([]byte(data), &webhook); err != nil {
+		return types.Webhook{}, false, err
+	}
+	return webhook, true, nil
+}
+
+func (s *sqlWebhookStore) List(ctx context.Context, tenantID string) ([]types.Webhook, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM webhooks WHERE tenant_id = ? ORDER BY created_at, webhook_id`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []types.Webhook
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var webhook types.Webhook
+		if err := json.Unmarshal([]byte(data), &webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *sqlWebhookStore) Save(ctx context.Context, webhook types.Webhook) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+	b, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO webhooks (tenant_id, webhook_id, created_at, data)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (tenant_id, webhook_id) DO UPDATE SET data = excluded.data`,
+		webhook.TenantID, webhook.WebhookID, webhook.CreatedAt, string(b))
+	return err
+}
+
+func (s *sqlWebhookStore) Delete(ctx context.Context, tenantID, webhookID string) (bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return false, err
+	}
+	if tenantID == "" || webhookID == "" {
+		return false, nil
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE tenant_id = ? AND webhook_id = ?`, tenantID, webhookID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *sqlWebhookStore) SaveDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateDelivery(delivery); err != nil {
+		return err
+	}
+	b, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (tenant_id, delivery_id, webhook_id, status, created_at, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, delivery_id) DO UPDATE SET
+			status = excluded.status,
+			data = excluded.data`,
+		delivery.TenantID, delivery.DeliveryID, delivery.WebhookID, delivery.Status, delivery.CreatedAt, string(b))
+	return err
+}
+
+func (s *sqlWebhookStore) ListDeliveries(ctx context.Context, tenantID string, filter DeliveryFilter) ([]types.WebhookDelivery, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, nil
+	}
+	where := []string{"tenant_id = ?"}
+	args := []any{tenantID}
+	if filter.WebhookID != "" {
+		where = append(where, "webhook_id = ?")
+		args = append(args, filter.WebhookID)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	query := `SELECT data FROM webhook_deliveries WHERE ` + strings.Join(where, " AND ") + ` ORDER BY created_at DESC, delivery_id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []types.WebhookDelivery
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var delivery types.WebhookDelivery
+		if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *sqlWebhookStore) ListPendingDeliveries(ctx context.Context) ([]types.WebhookDelivery, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM webhook_deliveries WHERE status = 'pending' ORDER BY created_at, delivery_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []types.WebhookDelivery
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var delivery types.WebhookDelivery
+		if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *sqlWebhookStore) PruneDeliveries(ctx context.Context, before string) (int, error) {
+	if err := ctxErr(ctx); err != nil {
+		return 0, err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
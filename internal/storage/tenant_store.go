@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+var (
+	// ErrTenantExists signals attempts to create a tenant that already exists.
+	ErrTenantExists = errors.New("tenant already exists")
+	// ErrInvalidTenant signals a tenant without an ID.
+	ErrInvalidTenant = errors.New("invalid tenant")
+)
+
+// TenantStore is the persistence port for tenant records. One store is shared by every
+// service, so reads must observe writes made by other processes.
+type TenantStore interface {
+	Get(ctx context.Context, tenantID string) (types.Tenant, bool, error)
+	// List returns every tenant ordered by tenant ID.
+	List(ctx context.Context) ([]types.Tenant, error)
+	Create(ctx context.Context, tenant types.Tenant) error
+	Save(ctx context.Context, tenant types.Tenant) error
+	// Delete removes a tenant and reports whether it existed.
+	Delete(ctx context.Context, tenantID string) (bool, error)
+	// Revision returns an opaque token that changes whenever any tenant is written, by
+	// any process. Callers poll it to detect changes without reloading every tenant.
+	Revision(ctx context.Context) (string, error)
+}
+
+// NewTenantStoreFromEnv constructs the default tenant store adapter.
+// AGENTOS_TENANT_STORE_DSN selects the SQL adapter; otherwise tenants are kept in the
+// JSON file AGENTOS_TENANT_STORE_FILE, falling back to ./data/tenants.json. Services share
+// tenants by pointing at the same database or file.
+func NewTenantStoreFromEnv() (TenantStore, error) {
+	if dsn := strings.TrimSpace(os.Getenv("AGENTOS_TENANT_STORE_DSN")); dsn != "" {
+		return NewSQLTenantStore(dsn)
+	}
+	return NewFileTenantStore(strings.TrimSpace(os.Getenv("AGENTOS_TENANT_STORE_FILE")))
+}
+
+// fileTenantStore keeps tenants in one JSON file. The file is read on every call so
+// writes by other processes are observed; concurrent writers in different processes may
+// overwrite each other's changes, so multi-replica deployments should use the SQL adapter.
+type fileTenantStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTenantStore returns a TenantStore persisted as JSON at path.
+func NewFileTenantStore(path string) (TenantStore, error) {
+	if path == "" {
+		path = filepath.Join("data", "tenants.json")
+	}
+	s := &fileTenantStore{path: path}
+	if _, err := s.read(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileTenantStore) Get(ctx context.Context, tenantID string) (types.Tenant, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Tenant{}, false, err
+	}
+	if tenantID == "" {
+		return types.Tenant{}, false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.read()
+	if err != nil {
+		return types.Tenant{}, false, err
+	}
+	t, ok := f.Tenants[tenantID]
+	return t, ok, nil
+}
+
+func (s *fileTenantStore) List(ctx context.Context) ([]types.Tenant, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	f, err := s.read()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]types.Tenant, 0, len(f.Tenants))
+	for _, t := range f.Tenants {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out, nil
+}
+
+func (s *fileTenantStore) Create(ctx context.Context, tenant types.Tenant) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if tenant.TenantID == "" {
+		return ErrInvalidTenant
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(func(tenants map[string]types.Tenant) (bool, error) {
+		if _, exists := tenants[tenant.TenantID]; exists {
+			return false, ErrTenantExists
+		}
+		tenants[tenant.TenantID] = tenant
+		return true, nil
+	})
+}
+
+func (s *fileTenantStore) Save(ctx context.Context, tenant types.Tenant) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if tenant.TenantID == "" {
+		return ErrInvalidTenant
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(func(tenants map[string]types.Tenant) (bool, error) {
+		tenants[tenant.TenantID] = tenant
+		return true, nil
+	})
+}
+
+func (s *fileTenantStore) Delete(ctx context.Context, tenantID string) (bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return false, err
+	}
+	if tenantID == "" {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := false
+	err := s.update(func(tenants map[string]types.Tenant) (bool, error) {
+		_, deleted = tenants[tenantID]
+		delete(tenants, tenantID)
+		return deleted, nil
+	})
+	return deleted && err == nil, err
+}
+
+func (s *fileTenantStore) Revision(ctx context.Context) (string, error) {
+	if err := ctxErr(ctx); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(f.Revision, 10), nil
+}
+
+// tenantFile is the on-disk layout; Revision is incremented on every write.
+type tenantFile struct {
+	Revision int64                   `json:"revision"`
+	Tenants  map[string]types.Tenant `json:"tenants"`
+}
+
+func (s *fileTenantStore) read() (tenantFile, error) {
+	f := tenantFile{Tenants: make(map[string]types.Tenant)}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return f, nil
+		}
+		return tenantFile{}, err
+	}
+	if len(b) == 0 {
+		return f, nil
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return tenantFile{}, err
+	}
+	if f.Tenants == nil {
+		f.Tenants = make(map[string]types.Tenant)
+	}
+	return f, nil
+}
+
+// update applies fn to the tenants on disk and writes them back with the next revision.
+// fn reports whether it changed anything.
+func (s *fileTenantStore) update(fn func(tenants map[string]types.Tenant) (bool, error)) error {
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+	changed, err := fn(f.Tenants)
+	if err != nil || !changed {
+		return err
+	}
+	f.Revision++
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestFileTenantStoreSharesTenantsAcrossInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	newStore := func() TenantStore {
+		store, err := NewFileTenantStore(path)
+		if err != nil {
+			t.Fatalf("NewFileTenantStore error: %v", err)
+		}
+		return store
+	}
+	testTenantStore(t, newStore(), newStore())
+}
+
+// testTenantStore checks CRUD and revisions against two TenantStores backed by the same
+// data, as two services sharing a tenant store would be.
+func testTenantStore(t *testing.T, store, other TenantStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := store.Create(ctx, types.Tenant{}); !errors.Is(err, ErrInvalidTenant) {
+		t.Fatalf("expected ErrInvalidTenant, got %v", err)
+	}
+	start, err := other.Revision(ctx)
+	if err != nil {
+		t.Fatalf("Revision error: %v", err)
+	}
+
+	if err := store.Create(ctx, types.Tenant{TenantID: "tnt_b", PlanTier: "free"}); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if err := store.Create(ctx, types.Tenant{TenantID: "tnt_a"}); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if err := other.Create(ctx, types.Tenant{TenantID: "tnt_a"}); !errors.Is(err, ErrTenantExists) {
+		t.Fatalf("expected ErrTenantExists across instances, got %v", err)
+	}
+	created, err := other.Revision(ctx)
+	if err != nil || created == start {
+		t.Fatalf("expected the revision to change after writes, got %q (was %q) err=%v", created, start, err)
+	}
+
+	if err := other.Save(ctx, types.Tenant{TenantID: "tnt_b", PlanTier: "standard"}); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	got, ok, err := store.Get(ctx, "tnt_b")
+	if err != nil || !ok || got.PlanTier != "standard" {
+		t.Fatalf("expected the other instance's update, got %+v ok=%v err=%v", got, ok, err)
+	}
+	saved, _ := store.Revision(ctx)
+	if saved == created {
+		t.Fatalf("expected the revision to change after Save")
+	}
+
+	if deleted, err := other.Delete(ctx, "tnt_a"); err != nil || !deleted {
+		t.Fatalf("expected delete, got %v err=%v", deleted, err)
+	}
+	if deleted, err := store.Delete(ctx, "tnt_a"); err != nil || deleted {
+		t.Fatalf("expected a second delete to be a no-op, got %v err=%v", deleted, err)
+	}
+	if rev, _ := store.Revision(ctx); rev == saved {
+		t.Fatalf("expected the revision to change after Delete")
+	}
+	list, err := store.List(ctx)
+	if err != nil || len(list) != 1 || list[0].TenantID != "tnt_b" {
+		t.Fatalf("expected only tnt_b, got %+v err=%v", list, err)
+	}
+}
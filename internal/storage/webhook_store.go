@@ -0,0 +1,348 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+var (
+	// ErrInvalidWebhook signals missing required webhook identity fields.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrInvalidDelivery signals missing required webhook delivery identity fields.
+	ErrInvalidDelivery = errors.New("invalid webhook delivery")
+)
+
+// WebhookStore is a tenant-scoped persistence port for webhook subscriptions and their
+// delivery log.
+type WebhookStore interface {
+	Get(ctx context.Context, tenantID, webhookID string) (types.Webhook, bool, error)
+	// List returns the tenant's webhooks, oldest first.
+	List(ctx context.Context, tenantID string) ([]types.Webhook, error)
+	Save(ctx context.Context, webhook types.Webhook) error
+	// Delete removes a webhook and reports whether it existed. Its deliveries are kept.
+	Delete(ctx context.Context, tenantID, webhookID string) (bool, error)
+
+	// SaveDelivery creates or replaces a delivery record.
+	SaveDelivery(ctx context.Context, delivery types.WebhookDelivery) error
+	// ListDeliveries returns the tenant's deliveries matching filter, newest first.
+	ListDeliveries(ctx context.Context, tenantID string, filter DeliveryFilter) ([]types.WebhookDelivery, error)
+	// ListPendingDeliveries returns the pending deliveries of every tenant, oldest first,
+	// so delivery can resume after a restart.
+	ListPendingDeliveries(ctx context.Context) ([]types.WebhookDelivery, error)
+	// PruneDeliveries removes the finished (succeeded or failed) deliveries created before
+	// the RFC 3339 UTC timestamp before and returns how many were removed.
+	PruneDeliveries(ctx context.Context, before string) (int, error)
+}
+
+// DeliveryFilter narrows WebhookStore.ListDeliveries results. Zero values match everything.
+type DeliveryFilter struct {
+	WebhookID string
+	Status    string
+	Limit     int // 0 means no limit
+}
+
+// fileWebhookStore keeps one JSON file per webhook under {dir}/{tenant_id}/ and one per
+// delivery under {dir}/{tenant_id}/deliveries/.
+type fileWebhookStore struct {
+	mu         sync.Mutex
+	dir        string
+	webhooks   map[string]types.Webhook         // key: tenant/{tenant_id}/webhooks/{webhook_id}
+	deliveries map[string]types.WebhookDelivery // key: tenant/{tenant_id}/deliveries/{delivery_id}
+}
+
+// NewWebhookStoreFromEnv constructs the default webhook store adapter.
+// AGENTOS_WEBHOOK_STORE_DSN selects the SQL adapter (it may share the run store database);
+// otherwise webhooks are kept as files under AGENTOS_WEBHOOK_STORE_DIR.
+func NewWebhookStoreFromEnv() (WebhookStore, error) {
+	if dsn := strings.TrimSpace(os.Getenv("AGENTOS_WEBHOOK_STORE_DSN")); dsn != "" {
+		return NewSQLWebhookStore(dsn)
+	}
+	dir := strings.TrimSpace(os.Getenv("AGENTOS_WEBHOOK_STORE_DIR"))
+	if dir == "" {
+		dir = filepath.Join("data", "agent-orchestrator", "webhooks")
+	}
+	return NewFileWebhookStore(dir)
+}
+
+// NewFileWebhookStore returns a file-backed WebhookStore.
+func NewFileWebhookStore(dir string) (WebhookStore, error) {
+	s := &fileWebhookStore{
+		dir:        dir,
+		webhooks:   make(map[string]types.Webhook),
+		deliveries: make(map[string]types.WebhookDelivery),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileWebhookStore) Get(ctx context.Context, tenantID, webhookID string) (types.Webhook, bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return types.Webhook{}, false, err
+	}
+	if tenantID == "" || webhookID == "" {
+		return types.Webhook{}, false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhook, ok := s.webhooks[webhookStorageKey(tenantID, webhookID)]
+	return webhook, ok, nil
+}
+
+func (s *fileWebhookStore) List(ctx context.Context, tenantID string) ([]types.Webhook, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var webhooks []types.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.TenantID == tenantID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if webhooks[i].CreatedAt != webhooks[j].CreatedAt {
+			return webhooks[i].CreatedAt < webhooks[j].CreatedAt
+		}
+		return webhooks[i].WebhookID < webhooks[j].WebhookID
+	})
+	return webhooks, nil
+}
+
+func (s *fileWebhookStore) Save(ctx context.Context, webhook types.Webhook) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks[webhookStorageKey(webhook.TenantID, webhook.WebhookID)] = webhook
+	return s.persist(filepath.Join(s.dir, webhook.TenantID, webhook.WebhookID+".json"), webhook)
+}
+
+func (s *fileWebhookStore) Delete(ctx context.Context, tenantID, webhookID string) (bool, error) {
+	if err := ctxErr(ctx); err != nil {
+		return false, err
+	}
+	if tenantID == "" || webhookID == "" {
+		return false, nil
+	}
+	key := webhookStorageKey(tenantID, webhookID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[key]; !ok {
+		return false, nil
+	}
+	delete(s.webhooks, key)
+	if s.dir == "" {
+		return true, nil
+	}
+	if err := os.Remove(filepath.Join(s.dir, tenantID, webhookID+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return true, err
+	}
+	return true, nil
+}
+
+func (s *fileWebhookStore) SaveDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if err := validateDelivery(delivery); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[deliveryStorageKey(delivery.TenantID, delivery.DeliveryID)] = delivery
+	return s.persist(filepath.Join(s.dir, delivery.TenantID, "deliveries", delivery.DeliveryID+".json"), delivery)
+}
+
+func (s *fileWebhookStore) ListDeliveries(ctx context.Context, tenantID string, filter DeliveryFilter) ([]types.WebhookDelivery, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deliveries []types.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.TenantID != tenantID {
+			continue
+		}
+		if filter.WebhookID != "" && delivery.WebhookID != filter.WebhookID {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt != deliveries[j].CreatedAt {
+			return deliveries[i].CreatedAt > deliveries[j].CreatedAt
+		}
+		return deliveries[i].DeliveryID > deliveries[j].DeliveryID
+	})
+	if filter.Limit > 0 && len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+func (s *fileWebhookStore) ListPendingDeliveries(ctx context.Context) ([]types.WebhookDelivery, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deliveries []types.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == "pending" {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt != deliveries[j].CreatedAt {
+			return deliveries[i].CreatedAt < deliveries[j].CreatedAt
+		}
+		return deliveries[i].DeliveryID < deliveries[j].DeliveryID
+	})
+	return deliveries, nil
+}
+
+func (s *fileWebhookStore) PruneDeliveries(ctx context.Context, before string) (int, error) {
+	if err := ctxErr(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := 0
+	for key, delivery := range s.deliveries {
+		if delivery.Status == "pending" || delivery.CreatedAt >= before {
+			continue
+		}
+		if s.dir != "" {
+			path := filepath.Join(s.dir, delivery.TenantID, "deliveries", delivery.DeliveryID+".json")
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return pruned, err
+			}
+		}
+		delete(s.deliveries, key)
+		pruned++
+	}
+	return pruned, nil
+}
+
+func (s *fileWebhookStore) load() error {
+	if s.dir == "" {
+		return nil
+	}
+
+	// Walk directory structure: {dir}/{tenant_id}/{webhook_id}.json and
+	// {dir}/{tenant_id}/deliveries/{delivery_id}.json
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			return nil
+		}
+		if filepath.Base(filepath.Dir(path)) == "deliveries" {
+			var delivery types.WebhookDelivery
+			if err := json.Unmarshal(b, &delivery); err != nil {
+				return err
+			}
+			if delivery.TenantID != "" && delivery.DeliveryID != "" {
+				s.deliveries[deliveryStorageKey(delivery.TenantID, delivery.DeliveryID)] = delivery
+			}
+			return nil
+		}
+		var webhook types.Webhook
+		if err := json.Unmarshal(b, &webhook); err != nil {
+			return err
+		}
+		if webhook.TenantID != "" && webhook.WebhookID != "" {
+			s.webhooks[webhookStorageKey(webhook.TenantID, webhook.WebhookID)] = webhook
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *fileWebhookStore) persist(path string, v any) error {
+	if s.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func validateWebhook(webhook types.Webhook) error {
+	if webhook.TenantID == "" || webhook.WebhookID == "" || webhook.URL == "" ||
+		strings.ContainsAny(webhook.WebhookID, `/\`) || webhook.WebhookID == "deliveries" {
+		return ErrInvalidWebhook
+	}
+	return nil
+}
+
+func validateDelivery(delivery types.WebhookDelivery) error {
+	if delivery.TenantID == "" || delivery.DeliveryID == "" || delivery.WebhookID == "" || strings.ContainsAny(delivery.DeliveryID, `/\`) {
+		return ErrInvalidDelivery
+	}
+	return nil
+}
+
+func webhookStorageKey(tenantID, webhookID string) string {
+	return filepath.ToSlash(filepath.Join("tenant", tenantID, "webhooks", webhookID))
+}
+
+func deliveryStorageKey(tenantID, deliveryID string) string {
+	return filepath.ToSlash(filepath.Join("tenant", tenantID, "deliveries", deliveryID))
+}
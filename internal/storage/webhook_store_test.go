@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestFileWebhookStorePersistsWebhooksAndDeliveries(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "webhooks")
+	store, err := NewFileWebhookStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileWebhookStore error: %v", err)
+	}
+	testWebhookStore(t, store)
+
+	reloaded, err := NewFileWebhookStore(dir)
+	if err != nil {
+		t.Fatalf("reload store error: %v", err)
+	}
+	webhooks, err := reloaded.List(context.Background(), "tnt_alpha")
+	if err != nil || len(webhooks) != 1 || webhooks[0].WebhookID != "whk_b" {
+		t.Fatalf("expected the remaining webhook after reload, got %+v err=%v", webhooks, err)
+	}
+	deliveries, err := reloaded.ListDeliveries(context.Background(), "tnt_alpha", DeliveryFilter{})
+	if err != nil || len(deliveries) != 3 {
+		t.Fatalf("expected deliveries after reload, got %+v err=%v", deliveries, err)
+	}
+
+	testWebhookDeliveryRetention(t, reloaded)
+	if reloaded, err = NewFileWebhookStore(dir); err != nil {
+		t.Fatalf("reload store error: %v", err)
+	}
+	if deliveries, _ := reloaded.ListDeliveries(context.Background(), "tnt_alpha", DeliveryFilter{}); len(deliveries) != 1 {
+		t.Fatalf("expected pruned deliveries removed from disk, got %+v", deliveries)
+	}
+}
+
+// testWebhookStore checks ordering, filtering, tenant scoping and deletion against any
+// WebhookStore.
+func testWebhookStore(t *testing.T, store WebhookStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := store.Save(ctx, types.Webhook{TenantID: "tnt_alpha", WebhookID: "whk_a"}); !errors.Is(err, ErrInvalidWebhook) {
+		t.Fatalf("expected ErrInvalidWebhook, got %v", err)
+	}
+	for _, webhook := range []types.Webhook{
+		{TenantID: "tnt_alpha", WebhookID: "whk_a", URL: "https://a.example", CreatedAt: "2026-01-01T00:00:00Z"},
+		{TenantID: "tnt_alpha", WebhookID: "whk_b", URL: "https://b.example", EventTypes: []string{"agentos.run.failed"}, CreatedAt: "2026-01-02T00:00:00Z"},
+		{TenantID: "tnt_beta", WebhookID: "whk_a", URL: "https://c.example", CreatedAt: "2026-01-01T00:00:00Z"},
+	} {
+		if err := store.Save(ctx, webhook); err != nil {
+			t.Fatalf("Save webhook error: %v", err)
+		}
+	}
+	webhooks, err := store.List(ctx, "tnt_alpha")
+	if err != nil || len(webhooks) != 2 || webhooks[0].WebhookID != "whk_a" {
+		t.Fatalf("expected tenant webhooks oldest first, got %+v err=%v", webhooks, err)
+	}
+	got, ok, err := store.Get(ctx, "tnt_alpha", "whk_b")
+	if err != nil || !ok || len(got.EventTypes) != 1 {
+		t.Fatalf("expected webhook with event filter, got %+v ok=%v err=%v", got, ok, err)
+	}
+
+	for _, delivery := range []types.WebhookDelivery{
+		{TenantID: "tnt_alpha", DeliveryID: "dlv_1", WebhookID: "whk_a", Status: "succeeded", CreatedAt: "2026-01-01T00:00:01Z"},
+		{TenantID: "tnt_alpha", DeliveryID: "dlv_2", WebhookID: "whk_b", Status: "pending", CreatedAt: "2026-01-01T00:00:02Z"},
+		{TenantID: "tnt_alpha", DeliveryID: "dlv_3", WebhookID: "whk_a", Status: "pending", CreatedAt: "2026-01-01T00:00:03Z"},
+		{TenantID: "tnt_beta", DeliveryID: "dlv_4", WebhookID: "whk_a", Status: "pending", CreatedAt: "2026-01-01T00:00:04Z"},
+	} {
+		if err := store.SaveDelivery(ctx, delivery); err != nil {
+			t.Fatalf("SaveDelivery error: %v", err)
+		}
+	}
+	// updating a delivery replaces it
+	if err := store.SaveDelivery(ctx, types.WebhookDelivery{TenantID: "tnt_alpha", DeliveryID: "dlv_3", WebhookID: "whk_a", Status: "failed", Attempts: 3, CreatedAt: "2026-01-01T00:00:03Z"}); err != nil {
+		t.Fatalf("SaveDelivery update error: %v", err)
+	}
+	deliveries, err := store.ListDeliveries(ctx, "tnt_alpha", DeliveryFilter{})
+	if err != nil || len(deliveries) != 3 || deliveries[0].DeliveryID != "dlv_3" || deliveries[0].Attempts != 3 {
+		t.Fatalf("expected tenant deliveries newest first, got %+v err=%v", deliveries, err)
+	}
+	deliveries, _ = store.ListDeliveries(ctx, "tnt_alpha", DeliveryFilter{WebhookID: "whk_a", Limit: 1})
+	if len(deliveries) != 1 || deliveries[0].DeliveryID != "dlv_3" {
+		t.Fatalf("expected webhook filter and limit, got %+v", deliveries)
+	}
+	deliveries, _ = store.ListDeliveries(ctx, "tnt_alpha", DeliveryFilter{Status: "pending"})
+	if len(deliveries) != 1 || deliveries[0].DeliveryID != "dlv_2" {
+		t.Fatalf("expected status filter, got %+v", deliveries)
+	}
+
+	if deleted, err := store.Delete(ctx, "tnt_alpha", "whk_a"); err != nil || !deleted {
+		t.Fatalf("expected delete to succeed, got %v err=%v", deleted, err)
+	}
+	if deleted, _ := store.Delete(ctx, "tnt_alpha", "whk_a"); deleted {
+		t.Fatalf("expected second delete to report missing webhook")
+	}
+	if _, ok, _ := store.Get(ctx, "tnt_beta", "whk_a"); !ok {
+		t.Fatalf("expected delete to stay tenant scoped")
+	}
+}
+
+// testWebhookDeliveryRetention checks pending delivery listing and pruning against a
+// WebhookStore that testWebhookStore ran on.
+func testWebhookDeliveryRetention(t *testing.T, store WebhookStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	pending, err := store.ListPendingDeliveries(ctx)
+	if err != nil || len(pending) != 2 || pending[0].DeliveryID != "dlv_2" || pending[1].TenantID != "tnt_beta" {
+		t.Fatalf("expected pending deliveries of every tenant oldest first, got %+v err=%v", pending, err)
+	}
+
+	pruned, err := store.PruneDeliveries(ctx, "2026-01-01T00:00:03Z")
+	if err != nil || pruned != 1 {
+		t.Fatalf("expected only the finished delivery before the cutoff pruned, got %d err=%v", pruned, err)
+	}
+	if pruned, _ := store.PruneDeliveries(ctx, "2026-02-01T00:00:00Z"); pruned != 1 {
+		t.Fatalf("expected pending deliveries kept, got %d pruned", pruned)
+	}
+	deliveries, _ := store.ListDeliveries(ctx, "tnt_alpha", DeliveryFilter{})
+	if len(deliveries) != 1 || deliveries[0].DeliveryID != "dlv_2" {
+		t.Fatalf("expected only the pending delivery left, got %+v", deliveries)
+	}
+}
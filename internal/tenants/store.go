@@ -1,25 +1,37 @@
 package tenants
 
 import (
+	"context"
 	"errors"
+	"log"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
 )
 
 var (
-	ErrInvalidTenant = errors.New("invalid tenant")
-	ErrTenantExists  = errors.New("tenant already exists")
+	ErrInvalidTenant = storage.ErrInvalidTenant
+	ErrTenantExists  = storage.ErrTenantExists
 	ErrNotFound      = errors.New("tenant not found")
+	ErrDefaultTenant = errors.New("cannot delete default tenant")
 )
 
+// Store serves tenants from memory. With a backend it writes through to it and
+// refreshes from it, so services sharing a backend see each other's tenants.
 type Store struct {
 	mu       sync.RWMutex
+	backend  storage.TenantStore // nil keeps tenants in memory only
+	revision string              // backend revision the cache was loaded at
+	writes   uint64              // local writes, so Refresh can detect writes racing it
 	tenants  map[string]types.Tenant
 	defaults map[string]struct{}
 }
 
+// NewStore returns a Store that keeps tenants in memory only.
 func NewStore() *Store {
 	return &Store{
 		tenants:  make(map[string]types.Tenant),
@@ -27,27 +39,120 @@ func NewStore() *Store {
 	}
 }
 
+// NewStoreFromEnv returns a Store backed by storage.NewTenantStoreFromEnv.
+func NewStoreFromEnv() (*Store, error) {
+	backend, err := storage.NewTenantStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewPersistentStore(backend)
+}
+
+// NewPersistentStore returns a Store backed by backend, loaded with its tenants.
+func NewPersistentStore(backend storage.TenantStore) (*Store, error) {
+	s := NewStore()
+	s.backend = backend
+	if _, err := s.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Refresh reloads the cache if the backend changed since it was last loaded and reports
+// whether it reloaded.
+func (s *Store) Refresh(ctx context.Context) (bool, error) {
+	if s.backend == nil {
+		return false, nil
+	}
+	revision, err := s.backend.Revision(ctx)
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	current, writes := revision == s.revision, s.writes
+	s.mu.RUnlock()
+	if current {
+		return false, nil
+	}
+	list, err := s.backend.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	loaded := make(map[string]types.Tenant, len(list))
+	for _, t := range list {
+		loaded[t.TenantID] = t
+	}
+
+	s.mu.Lock()
+	if s.writes != writes {
+		// a local write may be missing from the listing; retry on the next refresh
+		s.mu.Unlock()
+		return false, nil
+	}
+	s.tenants = loaded
+	s.revision = revision
+	s.mu.Unlock()
+	return true, nil
+}
+
+// Watch refreshes the cache from the backend every interval until ctx is canceled. It
+// returns immediately for a memory-only Store.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.backend == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("tenants: refresh failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// WatchFromEnv starts Watch with the interval in AGENTOS_TENANT_STORE_POLL_MS (default
+// 2000).
+func (s *Store) WatchFromEnv(ctx context.Context) {
+	interval := 2000
+	if v, err := strconv.Atoi(os.Getenv("AGENTOS_TENANT_STORE_POLL_MS")); err == nil && v > 0 {
+		interval = v
+	}
+	s.Watch(ctx, time.Duration(interval)*time.Millisecond)
+}
+
 // EnsureDefault seeds a tenant if it does not exist.
-func (s *Store) EnsureDefault(id string) {
+func (s *Store) EnsureDefault(id string) error {
 	if id == "" {
-		return
+		return nil
 	}
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, ok := s.tenants[id]; ok {
-		s.defaults[id] = struct{}{}
-		return
+	s.defaults[id] = struct{}{}
+	_, ok := s.tenants[id]
+	s.mu.Unlock()
+	if ok {
+		return nil
 	}
 	now := time.Now().UTC().Format(time.RFC3339)
-	s.tenants[id] = types.Tenant{
+	err := s.Create(types.Tenant{
 		TenantID:  id,
 		Name:      "default tenant",
 		Status:    "active",
 		PlanTier:  "default",
 		CreatedAt: now,
 		UpdatedAt: now,
+	})
+	if errors.Is(err, ErrTenantExists) {
+		// seeded concurrently by another service sharing the backend
+		_, err = s.Refresh(context.Background())
 	}
-	s.defaults[id] = struct{}{}
+	return err
 }
 
 func (s *Store) Create(t types.Tenant) error {
@@ -64,11 +169,19 @@ func (s *Store) Create(t types.Tenant) error {
 	t.UpdatedAt = now
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, exists := s.tenants[t.TenantID]; exists {
+	if s.backend != nil {
+		// the backend decides, as the cache may miss tenants of other services
+		if err := s.backend.Create(context.Background(), t); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	} else if _, exists := s.tenants[t.TenantID]; exists {
+		s.mu.Unlock()
 		return ErrTenantExists
 	}
 	s.tenants[t.TenantID] = t
+	s.writes++
+	s.mu.Unlock()
 	return nil
 }
 
@@ -77,9 +190,19 @@ func (s *Store) Update(id string, update types.Tenant) (types.Tenant, error) {
 		return types.Tenant{}, ErrInvalidTenant
 	}
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	cur, ok := s.tenants[id]
+	if s.backend != nil {
+		// merge into the stored record, which another service may have changed since
+		// the cache was refreshed
+		var err error
+		cur, ok, err = s.backend.Get(context.Background(), id)
+		if err != nil {
+			s.mu.Unlock()
+			return types.Tenant{}, err
+		}
+	}
 	if !ok {
+		s.mu.Unlock()
 		return types.Tenant{}, ErrNotFound
 	}
 
@@ -105,7 +228,15 @@ func (s *Store) Update(id string, update types.Tenant) (types.Tenant, error) {
 		cur.Policy = update.Policy
 	}
 	cur.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if s.backend != nil {
+		if err := s.backend.Save(context.Background(), cur); err != nil {
+			s.mu.Unlock()
+			return types.Tenant{}, err
+		}
+	}
 	s.tenants[id] = cur
+	s.writes++
+	s.mu.Unlock()
 	return cur, nil
 }
 
@@ -116,14 +247,27 @@ func (s *Store) Delete(id string) (types.Tenant, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	cur, ok := s.tenants[id]
+	if s.backend != nil {
+		var err error
+		cur, ok, err = s.backend.Get(context.Background(), id)
+		if err != nil {
+			return types.Tenant{}, err
+		}
+	}
 	if !ok {
 		return types.Tenant{}, ErrNotFound
 	}
 	// prevent deleting seeded defaults
 	if _, isDefault := s.defaults[id]; isDefault {
-		return types.Tenant{}, errors.New("cannot delete default tenant")
+		return types.Tenant{}, ErrDefaultTenant
+	}
+	if s.backend != nil {
+		if _, err := s.backend.Delete(context.Background(), id); err != nil {
+			return types.Tenant{}, err
+		}
 	}
 	delete(s.tenants, id)
+	s.writes++
 	return cur, nil
 }
 
@@ -1,8 +1,12 @@
 package tenants
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"testing"
 
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/storage"
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
 )
 
@@ -34,3 +38,65 @@ func TestStoreCRUD(t *testing.T) {
 		t.Fatalf("delete: %v", err)
 	}
 }
+
+func TestPersistentStoresShareTenants(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	newStore := func() *Store {
+		backend, err := storage.NewFileTenantStore(path)
+		if err != nil {
+			t.Fatalf("NewFileTenantStore: %v", err)
+		}
+		s, err := NewPersistentStore(backend)
+		if err != nil {
+			t.Fatalf("NewPersistentStore: %v", err)
+		}
+		return s
+	}
+	orchestrator, modelPolicy := newStore(), newStore()
+
+	if err := orchestrator.Create(types.Tenant{TenantID: "tnt_shared", PlanTier: "free"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := modelPolicy.Create(types.Tenant{TenantID: "tnt_shared"}); !errors.Is(err, ErrTenantExists) {
+		t.Fatalf("expected the shared backend to reject a duplicate before refresh, got %v", err)
+	}
+	if reloaded, err := modelPolicy.Refresh(ctx); err != nil || !reloaded {
+		t.Fatalf("expected a refresh after another store's write, got %v err=%v", reloaded, err)
+	}
+	if got, ok := modelPolicy.Get("tnt_shared"); !ok || got.PlanTier != "free" {
+		t.Fatalf("expected the other store's tenant after refresh, got %+v ok=%v", got, ok)
+	}
+
+	policy := &types.TenantPolicy{DeniedModels: []string{"local-stub-llm"}}
+	if _, err := orchestrator.Update("tnt_shared", types.Tenant{Policy: policy}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := modelPolicy.Refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if got, _ := modelPolicy.Get("tnt_shared"); got.Policy == nil || got.PlanTier != "free" {
+		t.Fatalf("expected the policy update to propagate, got %+v", got)
+	}
+	if reloaded, _ := modelPolicy.Refresh(ctx); reloaded {
+		t.Fatalf("expected no reload without changes")
+	}
+
+	if _, err := orchestrator.Delete("tnt_shared"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := modelPolicy.Refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, ok := modelPolicy.Get("tnt_shared"); ok {
+		t.Fatalf("expected the delete to propagate")
+	}
+
+	// tenants survive a restart
+	if err := orchestrator.Create(types.Tenant{TenantID: "tnt_durable"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, ok := newStore().Get("tnt_durable"); !ok {
+		t.Fatalf("expected tenants to be loaded from the backend")
+	}
+}
@@ -5,10 +5,21 @@ type Model struct {
 	Provider     string         `json:"provider,omitempty"`
 	DisplayName  string         `json:"display_name,omitempty"`
 	Capabilities map[string]any `json:"capabilities,omitempty"`
+	// ContextWindow is the maximum prompt plus completion tokens, when known.
+	ContextWindow int           `json:"context_window,omitempty"`
+	Pricing       *ModelPricing `json:"pricing,omitempty"`
+}
+
+// ModelPricing is the list price of a model in USD per million tokens.
+type ModelPricing struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
 }
 
 type ModelsListResponse struct {
 	Models []Model `json:"models"`
+	// Aliases are the model aliases in effect for the calling tenant.
+	Aliases map[string]string `json:"aliases,omitempty"`
 }
 
 // The repo's Model Policy OpenAPI uses an invoke-style request/response.
@@ -21,6 +32,9 @@ type ModelInvokeRequest struct {
 }
 
 type ModelInvokeResponse struct {
+	// ModelID and Provider identify the model that served the request, after aliases.
+	ModelID       string         `json:"model_id,omitempty"`
+	Provider      string         `json:"provider,omitempty"`
 	Output        map[string]any `json:"output"`
 	Usage         map[string]any `json:"usage,omitempty"`
 	CorrelationID string         `json:"correlation_id,omitempty"`
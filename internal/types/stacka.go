@@ -10,6 +10,8 @@ type HealthResponse struct {
 }
 
 type RunCreateRequest struct {
+	// AgentVersion pins the run to a specific agent version instead of the current one.
+	AgentVersion   string     `json:"agent_version,omitempty"`
 	Input          RunInput   `json:"input"`
 	Context        RunContext `json:"context"`
 	Tooling        Tooling    `json:"tooling"`
@@ -27,24 +29,86 @@ type RunGetResponse struct {
 	CorrelationID string `json:"correlation_id"`
 }
 
+type RunListResponse struct {
+	Runs          []Run  `json:"runs"`
+	NextCursor    string `json:"next_cursor,omitempty"`
+	CorrelationID string `json:"correlation_id"`
+}
+
 type RunCancelResponse struct {
 	Run           Run    `json:"run"`
 	CorrelationID string `json:"correlation_id"`
 }
 
+// RunDecisionRequest is the optional body of the :approve and :reject run actions.
+type RunDecisionRequest struct {
+	// ToolCallID, when set, must match the pending approval so a stale decision is rejected.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type RunDecisionResponse struct {
+	Run           Run    `json:"run"`
+	CorrelationID string `json:"correlation_id"`
+}
+
 type Run struct {
 	TenantID       string     `json:"tenant_id"`
 	AgentID        string     `json:"agent_id"`
+	AgentVersion   string     `json:"agent_version,omitempty"`
 	RunID          string     `json:"run_id"`
 	Status         string     `json:"status"`
 	CreatedAt      string     `json:"created_at"`
 	StartedAt      string     `json:"started_at,omitempty"`
 	CompletedAt    string     `json:"completed_at,omitempty"`
 	EventsURL      string     `json:"events_url"`
+	Input          *RunInput  `json:"input,omitempty"`
+	ModelID        string     `json:"model_id,omitempty"`
+	Instructions   string     `json:"instructions,omitempty"`
+	Tooling        *Tooling   `json:"tooling,omitempty"`
 	RunOptions     RunOptions `json:"run_options,omitempty"`
 	Output         *RunOutput `json:"output,omitempty"`
 	Error          *RunError  `json:"error,omitempty"`
 	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	// Context is the caller context from the create request, kept so retries can reuse it.
+	Context *RunContext `json:"context,omitempty"`
+	// SessionID links the run to a session whose history is fed to the model.
+	SessionID string `json:"session_id,omitempty"`
+	// Attempt numbers the runs of a retry chain, starting at 1.
+	Attempt int `json:"attempt,omitempty"`
+	// ParentRunID is the failed run this run retries.
+	ParentRunID string `json:"parent_run_id,omitempty"`
+	// RetriedBy is the run that retries this one, once a retry has been created.
+	RetriedBy string `json:"retried_by,omitempty"`
+	// PendingApproval is set while the run is waiting_for_input.
+	PendingApproval *RunApproval `json:"pending_approval,omitempty"`
+	// Checkpoint holds the executor's progress while the run is paused.
+	Checkpoint *RunCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// RunApproval describes the tool call a paused run needs a human decision on.
+type RunApproval struct {
+	StepID      string         `json:"step_id"`
+	ToolCallID  string         `json:"tool_call_id"`
+	ToolName    string         `json:"tool_name"`
+	Arguments   map[string]any `json:"arguments,omitempty"`
+	RequestedAt string         `json:"requested_at"`
+}
+
+// RunCheckpoint is the executor state needed to resume a paused run.
+type RunCheckpoint struct {
+	Steps        int              `json:"steps"`
+	ElapsedMs    int64            `json:"elapsed_ms"`
+	ToolResults  []map[string]any `json:"tool_results,omitempty"`
+	PendingCalls []ToolCall       `json:"pending_calls,omitempty"`
+	Approved     []string         `json:"approved_tool_call_ids,omitempty"`
+}
+
+// ToolCall is a tool invocation requested by the model.
+type ToolCall struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
 }
 
 type RunInput struct {
@@ -71,13 +135,27 @@ type ToolDescriptor struct {
 	Description string         `json:"description,omitempty"`
 	Config      map[string]any `json:"config,omitempty"`
 	InputSchema map[string]any `json:"input_schema,omitempty"`
+	// RequiresApproval pauses the run for a human decision before each call.
+	RequiresApproval bool `json:"requires_approval,omitempty"`
 }
 
 type RunOptions struct {
-	Priority     string `json:"priority"`
-	TimeoutMs    int    `json:"timeout_ms"`
-	MaxSteps     int    `json:"max_steps"`
-	StreamEvents bool   `json:"stream_events,omitempty"`
+	Priority     string       `json:"priority"`
+	TimeoutMs    int          `json:"timeout_ms"`
+	MaxSteps     int          `json:"max_steps"`
+	StreamEvents bool         `json:"stream_events,omitempty"`
+	Retry        *RetryPolicy `json:"retry,omitempty"`
+}
+
+// RetryPolicy makes the executor retry a failed or timed out run automatically.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first; 0 or 1 disables
+	// automatic retries.
+	MaxAttempts int `json:"max_attempts"`
+	// BackoffMs delays the first retry; the delay doubles on each later attempt.
+	BackoffMs int `json:"backoff_ms,omitempty"`
+	// RetryOn lists the RunError codes to retry. When empty, errors marked retryable are.
+	RetryOn []string `json:"retry_on,omitempty"`
 }
 
 type RunOutput struct {
@@ -125,6 +203,42 @@ type Agent struct {
 	Status      string `json:"status"`
 	CreatedAt   string `json:"created_at"`
 	UpdatedAt   string `json:"updated_at"`
+
+	Definition AgentDefinition `json:"definition"`
+}
+
+// AgentDefinition is what a run of the agent executes: runs created for the agent inherit
+// its model, instructions and tools, and its run options fill in fields the caller leaves
+// unset.
+type AgentDefinition struct {
+	ModelID      string           `json:"model_id,omitempty"`
+	Instructions string           `json:"instructions,omitempty"`
+	Tools        []ToolDescriptor `json:"tools,omitempty"`
+	RunOptions   RunOptions       `json:"run_options"`
+}
+
+// AgentVersion is an immutable snapshot of an agent's metadata and definition. Versions
+// are numbered "1", "2", ... in creation order; SourceVersion is set when the version was
+// produced by rolling back to an earlier one.
+type AgentVersion struct {
+	TenantID      string          `json:"tenant_id"`
+	AgentID       string          `json:"agent_id"`
+	Version       string          `json:"version"`
+	Name          string          `json:"name"`
+	Description   string          `json:"description,omitempty"`
+	Definition    AgentDefinition `json:"definition"`
+	SourceVersion string          `json:"source_version,omitempty"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+type AgentVersionListResponse struct {
+	Versions      []AgentVersion `json:"versions"`
+	CorrelationID string         `json:"correlation_id"`
+}
+
+type AgentVersionGetResponse struct {
+	AgentVersion  AgentVersion `json:"agent_version"`
+	CorrelationID string       `json:"correlation_id"`
 }
 
 type AgentListResponse struct {
@@ -136,3 +250,108 @@ type AgentGetResponse struct {
 	Agent         Agent  `json:"agent"`
 	CorrelationID string `json:"correlation_id"`
 }
+
+// AgentCreateRequest registers a new agent as version "1". AgentID is generated when empty
+// and Status defaults to "draft".
+type AgentCreateRequest struct {
+	AgentID     string `json:"agent_id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status,omitempty"`
+
+	Definition AgentDefinition `json:"definition"`
+}
+
+// AgentUpdateRequest replaces an agent's mutable metadata and records the result as a new
+// agent version. An empty Status keeps the current status and a nil Definition keeps the
+// current definition.
+type AgentUpdateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status,omitempty"`
+
+	Definition *AgentDefinition `json:"definition,omitempty"`
+}
+
+// Session accumulates the turns of completed runs that share a session_id.
+type Session struct {
+	TenantID   string        `json:"tenant_id"`
+	SessionID  string        `json:"session_id"`
+	CreatedAt  string        `json:"created_at"`
+	UpdatedAt  string        `json:"updated_at"`
+	TurnCount  int           `json:"turn_count"`
+	TokenCount int           `json:"token_count"`
+	Turns      []SessionTurn `json:"turns,omitempty"`
+}
+
+// SessionTurn is one user input or assistant output of a run.
+type SessionTurn struct {
+	RunID     string `json:"run_id"`
+	Role      string `json:"role"`
+	Text      string `json:"text"`
+	Tokens    int    `json:"tokens"`
+	CreatedAt string `json:"created_at"`
+}
+
+type SessionListResponse struct {
+	Sessions      []Session `json:"sessions"`
+	CorrelationID string    `json:"correlation_id"`
+}
+
+type SessionGetResponse struct {
+	Session       Session `json:"session"`
+	CorrelationID string  `json:"correlation_id"`
+}
+
+// Webhook subscribes a tenant endpoint to run lifecycle events.
+type Webhook struct {
+	TenantID  string `json:"tenant_id"`
+	WebhookID string `json:"webhook_id"`
+	URL       string `json:"url"`
+	// EventTypes filters the delivered event types; empty delivers all run lifecycle events.
+	EventTypes []string `json:"event_types,omitempty"`
+	// Secret keys the HMAC signature of deliveries. Only the create response returns it.
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type WebhookCreateRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types,omitempty"`
+	// Secret is generated when empty.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookResponse struct {
+	Webhook       Webhook `json:"webhook"`
+	CorrelationID string  `json:"correlation_id"`
+}
+
+type WebhookListResponse struct {
+	Webhooks      []Webhook `json:"webhooks"`
+	CorrelationID string    `json:"correlation_id"`
+}
+
+// WebhookDelivery records the delivery of one event to one webhook across its attempts.
+type WebhookDelivery struct {
+	TenantID       string `json:"tenant_id"`
+	DeliveryID     string `json:"delivery_id"`
+	WebhookID      string `json:"webhook_id"`
+	URL            string `json:"url"`
+	EventID        string `json:"event_id"`
+	EventType      string `json:"event_type"`
+	RunID          string `json:"run_id"`
+	Status         string `json:"status"` // pending, succeeded, failed
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status,omitempty"`
+	Error          string `json:"error,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries    []WebhookDelivery `json:"deliveries"`
+	CorrelationID string            `json:"correlation_id"`
+}
@@ -19,6 +19,9 @@ type TenantPolicy struct {
 	AllowedModels []string     `json:"allowed_models,omitempty"`
 	DeniedModels  []string     `json:"denied_models,omitempty"`
 	TokenBudget   *TokenBudget `json:"token_budget,omitempty"`
+	// ModelAliases map names such as default, fast or smart to model IDs for this
+	// tenant, overriding model-policy's aliases.
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
 }
 
 // TokenBudget defines token usage limits per tenant.
@@ -0,0 +1,242 @@
+package modelpolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/secrets"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// catalogFile is the layout of the models file named by AGENTOS_MODELS_FILE.
+type catalogFile struct {
+	Models []catalogModel `json:"models"`
+	// Aliases map names such as default, fast or smart to model IDs or routes.
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Routes split requests for a name across models by weight, e.g. for A/B tests.
+	Routes map[string][]weightedTarget `json:"routes,omitempty"`
+	// Fallbacks list, per model ID, the models tried in order when its provider fails or
+	// its circuit is open.
+	Fallbacks map[string][]string `json:"fallbacks,omitempty"`
+}
+
+// catalogModel declares one model: its public description plus how to reach it.
+type catalogModel struct {
+	types.Model
+	// Endpoint is the provider base URL, e.g. http://vllm:8000/v1 for provider openai.
+	Endpoint string `json:"endpoint,omitempty"`
+	// UpstreamModel is the model name sent to the provider; defaults to model_id.
+	UpstreamModel string `json:"upstream_model,omitempty"`
+	// Credentials names the secret holding the API key, resolved with secrets.Loader
+	// (env var, NAME_FILE or the external provider); the key never appears in the file.
+	Credentials string `json:"credentials,omitempty"`
+	TimeoutMS   int    `json:"timeout_ms,omitempty"`
+	// Disabled hides the model, including a built-in model of the same ID.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// loadCatalog reads the models file at path and replaces the registry's catalog with it.
+// The file is validated as a whole: on error the previous catalog stays in place. It
+// reports whether the catalog changed. A file is reloaded only when its content or the
+// value of a credential it names changed, so a rotated secret is picked up; a rejected
+// file is reported once, until it or a credential changes again.
+func (r *registry) loadCatalog(path string, loader *secrets.Loader) (changed bool, err error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("models file: %w", err)
+	}
+	var f catalogFile
+	parseErr := json.Unmarshal(raw, &f)
+	h := sha256.New()
+	h.Write(raw)
+	credentials, credentialErrs := map[string]string{}, map[string]error{}
+	for _, m := range f.Models {
+		if m.Credentials == "" || m.Disabled {
+			continue
+		}
+		if _, seen := credentials[m.Credentials]; seen {
+			continue
+		}
+		// a missing secret resolves to "", so its later appearance changes the sum
+		v, err := loader.Load(m.Credentials)
+		credentials[m.Credentials], credentialErrs[m.Credentials] = v, err
+		fmt.Fprintf(h, "\x00%s\x00%s", m.Credentials, v)
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	r.mu.RLock()
+	unchanged := sum == r.catalogSum || sum == r.rejectedSum
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	defer func() {
+		if err != nil {
+			r.mu.Lock()
+			r.rejectedSum = sum
+			r.mu.Unlock()
+		}
+	}()
+
+	if parseErr != nil {
+		return false, fmt.Errorf("models file %s: %w", path, parseErr)
+	}
+	catalog := make(map[string]*providerEntry, len(f.Models))
+	for i, m := range f.Models {
+		m.ModelID = strings.TrimSpace(m.ModelID)
+		if m.ModelID == "" {
+			return false, fmt.Errorf("models file %s: model %d has no model_id", path, i)
+		}
+		if _, dup := catalog[m.ModelID]; dup {
+			return false, fmt.Errorf("models file %s: duplicate model_id %q", path, m.ModelID)
+		}
+		if m.Disabled {
+			catalog[m.ModelID] = nil
+			continue
+		}
+		if err := credentialErrs[m.Credentials]; err != nil {
+			return false, fmt.Errorf("models file %s: model %q: %w", path, m.ModelID, err)
+		}
+		impl, err := m.provider(credentials)
+		if err != nil {
+			return false, fmt.Errorf("models file %s: model %q: %w", path, m.ModelID, err)
+		}
+		catalog[m.ModelID] = &providerEntry{model: m.Model, impl: impl, circuit: circuitKey(m.Provider, impl)}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enabled := func(id string) bool {
+		if entry, inCatalog := catalog[id]; inCatalog {
+			return entry != nil
+		}
+		_, builtin := r.model[id]
+		return builtin
+	}
+	for name, targets := range f.Routes {
+		total := 0
+		for _, t := range targets {
+			if !enabled(t.ModelID) || t.Weight < 0 {
+				return false, fmt.Errorf("models file %s: route %q: invalid target %q (weight %d)", path, name, t.ModelID, t.Weight)
+			}
+			total += t.Weight
+		}
+		if name == "" || total == 0 {
+			return false, fmt.Errorf("models file %s: route %q needs a target with a positive weight", path, name)
+		}
+	}
+	for name, target := range f.Aliases {
+		if _, isRoute := f.Routes[target]; name == "" || (!isRoute && !enabled(target)) {
+			return false, fmt.Errorf("models file %s: alias %q targets unknown or disabled model %q", path, name, target)
+		}
+	}
+	for id, chain := range f.Fallbacks {
+		for _, target := range chain {
+			if !enabled(target) {
+				return false, fmt.Errorf("models file %s: fallback of %q: unknown or disabled model %q", path, id, target)
+			}
+		}
+	}
+	replaced := r.catalog
+	r.catalog = catalog
+	for _, entry := range replaced {
+		// in-flight calls keep their connections; idle ones would otherwise linger
+		if entry == nil {
+			continue
+		}
+		if p, ok := entry.impl.(*openAIProvider); ok {
+			p.client.CloseIdleConnections()
+		}
+	}
+	r.catalogAliases = f.Aliases
+	r.routes = f.Routes
+	r.fallbacks = f.Fallbacks
+	r.catalogSum = sum
+	r.rejectedSum = [32]byte{}
+	return true, nil
+}
+
+// provider builds the provider serving m; credentials holds the resolved secrets by name.
+func (m catalogModel) provider(credentials map[string]string) (provider, error) {
+	switch m.Provider {
+	case "stub":
+		return stubProvider{}, nil
+	case "openai":
+		if strings.TrimSpace(m.Endpoint) == "" {
+			return nil, errors.New("provider openai requires an endpoint")
+		}
+		apiKey := credentials[m.Credentials]
+		if m.Credentials != "" && apiKey == "" {
+			return nil, fmt.Errorf("secret %s is required but missing", m.Credentials)
+		}
+		upstream := m.UpstreamModel
+		if upstream == "" {
+			upstream = m.ModelID
+		}
+		timeout := 60 * time.Second
+		if m.TimeoutMS > 0 {
+			timeout = time.Duration(m.TimeoutMS) * time.Millisecond
+		}
+		return newOpenAIProvider(m.Endpoint, apiKey, upstream, timeout), nil
+	case "":
+		return nil, errors.New("provider required")
+	default:
+		return nil, fmt.Errorf("unknown provider %q", m.Provider)
+	}
+}
+
+// catalogFromEnv loads the models file AGENTOS_MODELS_FILE into r, if set, and returns
+// its path.
+func catalogFromEnv(r *registry) (string, error) {
+	path := strings.TrimSpace(os.Getenv("AGENTOS_MODELS_FILE"))
+	if path == "" {
+		return "", nil
+	}
+	if _, err := r.loadCatalog(path, secrets.NewLoader()); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// watchCatalog reloads the models file at path every interval until ctx is canceled. A
+// file that fails to load is logged once and the previous catalog kept.
+func (r *registry) watchCatalog(ctx context.Context, path string, interval time.Duration) {
+	if path == "" {
+		return
+	}
+	loader := secrets.NewLoader()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				changed, err := r.loadCatalog(path, loader)
+				if err != nil {
+					log.Printf("model-policy: keeping previous model catalog: %v", err)
+				} else if changed {
+					log.Printf("model-policy: reloaded model catalog from %s", path)
+				}
+			}
+		}
+	}()
+}
+
+// catalogPollInterval returns AGENTOS_MODELS_POLL_MS (default 5000).
+func catalogPollInterval() time.Duration {
+	interval := 5000
+	if v, err := strconv.Atoi(os.Getenv("AGENTOS_MODELS_POLL_MS")); err == nil && v > 0 {
+		interval = v
+	}
+	return time.Duration(interval) * time.Millisecond
+}
@@ -0,0 +1,188 @@
+package modelpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/secrets"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func writeModelsFile(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write models file: %v", err)
+	}
+}
+
+func listModels(t *testing.T, s *Server) map[string]types.Model {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("X-Tenant-Id", "tnt_catalog")
+	s.Handler().ServeHTTP(rec, req)
+	var resp types.ModelsListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode models: %v body=%s", err, rec.Body.String())
+	}
+	out := map[string]types.Model{}
+	for _, m := range resp.Models {
+		out[m.ModelID] = m
+	}
+	return out
+}
+
+func TestCatalogServesDeclaredModels(t *testing.T) {
+	var auth, model string
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		model, _ = body["model"].(string)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`))
+	}))
+	t.Cleanup(fake.Close)
+	t.Setenv("CATALOG_TEST_KEY", "sk-catalog")
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[
+		{"model_id":"vllm-small","provider":"openai","display_name":"Small","endpoint":"`+fake.URL+`/v1",
+		 "upstream_model":"qwen-small","credentials":"CATALOG_TEST_KEY","context_window":8192,
+		 "pricing":{"input_per_mtok":0.1,"output_per_mtok":0.4},"capabilities":{"chat":true}}]}`)
+	t.Setenv("AGENTOS_MODELS_FILE", path)
+	s := newTestServer(t)
+
+	models := listModels(t, s)
+	small, ok := models["vllm-small"]
+	if !ok || small.ContextWindow != 8192 || small.Pricing == nil || small.Pricing.OutputPerMTok != 0.4 {
+		t.Fatalf("expected the catalog model with its metadata, got %+v", models)
+	}
+	if _, ok := models["local-stub-llm"]; !ok {
+		t.Fatalf("expected built-in models alongside the catalog, got %+v", models)
+	}
+
+	rec := invokeModel(t, s, "tnt_catalog", types.ModelInvokeRequest{Operation: "chat", ModelID: "vllm-small", Input: map[string]any{"text": "hello"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if auth != "Bearer sk-catalog" || model != "qwen-small" {
+		t.Fatalf("expected the resolved credentials and upstream model, got auth=%q model=%q", auth, model)
+	}
+}
+
+func TestCatalogReloadDisablesModelsAndKeepsPreviousOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[{"model_id":"stub-a","provider":"stub"}]}`)
+	r := newRegistry()
+	loader := secrets.NewLoader()
+	if _, err := r.loadCatalog(path, loader); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if changed, err := r.loadCatalog(path, loader); changed || err != nil {
+		t.Fatalf("expected an unchanged file to be skipped, got %v %v", changed, err)
+	}
+
+	writeModelsFile(t, path, `{"models":[{"model_id":"stub-a","provider":"stub","disabled":true},{"model_id":"local-stub-llm","disabled":true}]}`)
+	if changed, err := r.loadCatalog(path, loader); !changed || err != nil {
+		t.Fatalf("expected a reload, got %v %v", changed, err)
+	}
+	if _, ok := r.Resolve("stub-a", nil); ok {
+		t.Fatalf("expected disabled model not to resolve")
+	}
+	if models := r.Models(); len(models) != 0 {
+		t.Fatalf("expected the disabled built-in model to be hidden, got %+v", models)
+	}
+
+	for _, bad := range []string{
+		`{"models":[`,
+		`{"models":[{"model_id":"x","provider":"nope"}]}`,
+		`{"models":[{"model_id":"x","provider":"openai"}]}`,
+		`{"models":[{"model_id":"x","provider":"openai","endpoint":"http://e","credentials":"CATALOG_MISSING_KEY"}]}`,
+		`{"models":[{"model_id":"x","provider":"stub"},{"model_id":"x","provider":"stub"}]}`,
+		`{"models":[{"model_id":"x","provider":"stub"}],"aliases":{"fast":"missing"}}`,
+	} {
+		writeModelsFile(t, path, bad)
+		if _, err := r.loadCatalog(path, loader); err == nil {
+			t.Fatalf("expected an error for %s", bad)
+		}
+	}
+	if _, ok := r.Resolve("stub-a", nil); ok {
+		t.Fatalf("expected the previous catalog to stay in place after errors")
+	}
+}
+
+func TestCatalogReloadPicksUpRotatedCredentialsAndReportsRejectionsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[{"model_id":"vllm","provider":"openai","endpoint":"http://vllm/v1","credentials":"CATALOG_ROTATED_KEY"}]}`)
+	t.Setenv("CATALOG_ROTATED_KEY", "sk-old")
+	r := newRegistry()
+	loader := secrets.NewLoader()
+	if _, err := r.loadCatalog(path, loader); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	t.Setenv("CATALOG_ROTATED_KEY", "sk-new")
+	if changed, err := r.loadCatalog(path, loader); !changed || err != nil {
+		t.Fatalf("expected a rotated secret to reload the catalog, got %v %v", changed, err)
+	}
+	if rt, ok := r.Resolve("vllm", nil); !ok || rt[0].impl.(*openAIProvider).apiKey != "sk-new" {
+		t.Fatalf("expected the provider rebuilt with the new secret")
+	}
+
+	t.Setenv("CATALOG_ROTATED_KEY", "")
+	if _, err := r.loadCatalog(path, loader); err == nil {
+		t.Fatalf("expected an error once the secret is gone")
+	}
+	if changed, err := r.loadCatalog(path, loader); changed || err != nil {
+		t.Fatalf("expected the rejected catalog reported once, got %v %v", changed, err)
+	}
+	t.Setenv("CATALOG_ROTATED_KEY", "sk-newer")
+	if changed, err := r.loadCatalog(path, loader); !changed || err != nil {
+		t.Fatalf("expected a reload once the secret is back, got %v %v", changed, err)
+	}
+}
+
+func TestCatalogReloadClosesIdleConnectionsOfReplacedProviders(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	fake := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"choices": []map[string]any{{"message": map[string]any{"content": "ok"}}}})
+	}))
+	fake.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			select {
+			case closed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	fake.Start()
+	t.Cleanup(fake.Close)
+
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[{"model_id":"vllm","provider":"openai","endpoint":"`+fake.URL+`/v1"}]}`)
+	r := newRegistry()
+	loader := secrets.NewLoader()
+	if _, err := r.loadCatalog(path, loader); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	rt, _ := r.Resolve("vllm", nil)
+	if _, _, err := rt[0].impl.Invoke(context.Background(), types.ModelInvokeRequest{Input: map[string]any{"text": "hi"}}); err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+
+	writeModelsFile(t, path, `{"models":[{"model_id":"vllm","provider":"openai","endpoint":"`+fake.URL+`/v1","timeout_ms":5000}]}`)
+	if changed, err := r.loadCatalog(path, loader); !changed || err != nil {
+		t.Fatalf("reload: %v %v", changed, err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the replaced provider's idle connection to be closed")
+	}
+}
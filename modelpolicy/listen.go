@@ -1,8 +1,15 @@
 package modelpolicy
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 func ListenAndServe(addr, version string) error {
-	s := New(version)
+	s, err := New(version)
+	if err != nil {
+		return err
+	}
+	s.Start(context.Background())
 	return http.ListenAndServe(addr, s.Handler())
 }
@@ -0,0 +1,443 @@
+package modelpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/secrets"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// openAIOptions are the ModelInvokeRequest.Options passed through to the chat
+// completions request; other options (e.g. deny) are for model-policy itself.
+var openAIOptions = []string{"temperature", "top_p", "max_tokens", "stop", "seed", "presence_penalty", "frequency_penalty", "response_format", "tool_choice", "user"}
+
+// openAIProvider speaks the OpenAI chat completions wire format to any compatible
+// server (OpenAI, llama.cpp, vLLM, ...).
+type openAIProvider struct {
+	baseURL string // e.g. http://localhost:8000/v1
+	apiKey  string // sent as a bearer token when set
+	model   string // model name sent upstream
+	// timeout bounds a whole Invoke call, but only the wait for response headers of a
+	// Stream call, which may run for as long as the model generates.
+	timeout time.Duration
+	client  *http.Client
+}
+
+func newOpenAIProvider(baseURL, apiKey, model string, timeout time.Duration) *openAIProvider {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return &openAIProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		timeout: timeout,
+		client:  &http.Client{Transport: transport},
+	}
+}
+
+// registerOpenAIFromEnv registers the models served by the OpenAI-compatible server at
+// AGENTOS_OPENAI_BASE_URL. AGENTOS_OPENAI_MODELS lists them comma separated, each as
+// model_id or model_id=upstream_name; the API key is the secret AGENTOS_OPENAI_API_KEY.
+func registerOpenAIFromEnv(r *registry) error {
+	baseURL := strings.TrimSpace(os.Getenv("AGENTOS_OPENAI_BASE_URL"))
+	if baseURL == "" {
+		return nil
+	}
+	apiKey, err := secrets.NewLoader().Load("AGENTOS_OPENAI_API_KEY")
+	if err != nil {
+		return err
+	}
+	timeout := 60 * time.Second
+	if ms, err := strconv.Atoi(os.Getenv("AGENTOS_OPENAI_TIMEOUT_MS")); err == nil && ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	registered := 0
+	for _, entry := range strings.Split(os.Getenv("AGENTOS_OPENAI_MODELS"), ",") {
+		modelID, upstream, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		modelID, upstream = strings.TrimSpace(modelID), strings.TrimSpace(upstream)
+		if modelID == "" {
+			continue
+		}
+		if upstream == "" {
+			upstream = modelID
+		}
+		r.register(types.Model{
+			ModelID:      modelID,
+			Provider:     "openai",
+			DisplayName:  upstream,
+			Capabilities: map[string]any{"chat": true, "tools": true},
+		}, newOpenAIProvider(baseURL, apiKey, upstream, timeout))
+		registered++
+	}
+	if registered == 0 {
+		return fmt.Errorf("AGENTOS_OPENAI_MODELS must list at least one model when AGENTOS_OPENAI_BASE_URL is set")
+	}
+	return nil
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// openAIChunk is one server-sent event of a streamed chat completion.
+type openAIChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+func (p *openAIProvider) Invoke(ctx context.Context, req types.ModelInvokeRequest) (map[string]any, map[string]any, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	resp, err := p.post(ctx, p.chatRequest(req))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, nil, fmt.Errorf("provider response: %w", err)
+	}
+
+	var decoded openAIResponse
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, nil, fmt.Errorf("provider response: %w", err)
+	}
+	if len(decoded.Choices) == 0 {
+		return nil, nil, fmt.Errorf("provider response has no choices")
+	}
+	choice := decoded.Choices[0]
+	return openAIOutput(choice.Message, choice.FinishReason), openAIUsageOf(req.ModelID, decoded.Usage), nil
+}
+
+// Stream requests a streamed chat completion and emits its text and tool call deltas as
+// they arrive. Usage is reported by the server in the final chunk. The provider timeout
+// applies until the response headers arrive; the stream itself ends with ctx.
+func (p *openAIProvider) Stream(ctx context.Context, req types.ModelInvokeRequest, emit func(map[string]any) error) (map[string]any, map[string]any, error) {
+	body := p.chatRequest(req)
+	body["stream"] = true
+	body["stream_options"] = map[string]any{"include_usage": true}
+	resp, err := p.post(ctx, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		message openAIMessage
+		text    strings.Builder
+		finish  string
+		usage   *openAIUsage
+		done    bool
+	)
+	message.Role = "assistant"
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 8<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk openAIChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, nil, fmt.Errorf("provider stream: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			finish = choice.FinishReason
+		}
+		delta := map[string]any{}
+		if choice.Delta.Content != "" {
+			text.WriteString(choice.Delta.Content)
+			delta["text"] = choice.Delta.Content
+		}
+		if len(choice.Delta.ToolCalls) > 0 {
+			calls := make([]any, 0, len(choice.Delta.ToolCalls))
+			for _, c := range choice.Delta.ToolCalls {
+				// tool calls arrive in fragments: the first carries the ID and name, the
+				// rest append to the arguments
+				for len(message.ToolCalls) <= c.Index {
+					message.ToolCalls = append(message.ToolCalls, openAIToolCall{Type: "function"})
+				}
+				call := &message.ToolCalls[c.Index]
+				if c.ID != "" {
+					call.ID = c.ID
+				}
+				call.Function.Name += c.Function.Name
+				call.Function.Arguments += c.Function.Arguments
+				calls = append(calls, map[string]any{
+					"index":    c.Index,
+					"id":       c.ID,
+					"function": map[string]any{"name": c.Function.Name, "arguments": c.Function.Arguments},
+				})
+			}
+			delta["tool_calls"] = calls
+		}
+		if len(delta) > 0 {
+			if err := emit(delta); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("provider stream: %w", err)
+	}
+	if !done {
+		// the connection closed mid-stream: the output is truncated
+		var partial map[string]any
+		if usage != nil {
+			partial = openAIUsageOf(req.ModelID, usage)
+		}
+		return nil, partial, errors.New("provider stream ended before [DONE]")
+	}
+	message.Content = text.String()
+	return openAIOutput(message, finish), openAIUsageOf(req.ModelID, usage), nil
+}
+
+// post sends a chat completions request and returns the response of a 2xx status.
+func (p *openAIProvider) post(ctx context.Context, body map[string]any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("provider unavailable: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, &providerStatusError{StatusCode: resp.StatusCode, Message: upstreamErrorMessage(raw)}
+	}
+	return resp, nil
+}
+
+// providerStatusError is a non-2xx response from a provider.
+type providerStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *providerStatusError) Error() string {
+	return fmt.Sprintf("provider returned HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// rejected reports whether the provider refused the request itself (a 4xx other than
+// 408 and 429), as opposed to failing to serve it.
+func (e *providerStatusError) rejected() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
+func openAIOutput(message openAIMessage, finishReason string) map[string]any {
+	output := map[string]any{
+		"type":          "text",
+		"text":          message.Content,
+		"finish_reason": finishReason,
+	}
+	if len(message.ToolCalls) > 0 {
+		calls := make([]any, 0, len(message.ToolCalls))
+		for _, c := range message.ToolCalls {
+			calls = append(calls, map[string]any{
+				"id":       c.ID,
+				"type":     "function",
+				"function": map[string]any{"name": c.Function.Name, "arguments": c.Function.Arguments},
+			})
+		}
+		output["tool_calls"] = calls
+	}
+	return output
+}
+
+func openAIUsageOf(modelID string, u *openAIUsage) map[string]any {
+	usage := map[string]any{
+		"model_id":  modelID,
+		"provider":  "openai",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	if u != nil {
+		total := u.TotalTokens
+		if total == 0 {
+			total = u.PromptTokens + u.CompletionTokens
+		}
+		usage["prompt_tokens"] = u.PromptTokens
+		usage["completion_tokens"] = u.CompletionTokens
+		usage["total_tokens"] = total
+	}
+	return usage
+}
+
+// chatRequest maps an invoke request to a chat completions request. Input keys:
+// instructions (system message), history (session turns), messages (passed through),
+// text (user message), tools (tool descriptors) and tool_results (previous tool calls
+// and their results).
+func (p *openAIProvider) chatRequest(req types.ModelInvokeRequest) map[string]any {
+	var messages []any
+	if s, ok := req.Input["instructions"].(string); ok && s != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: s})
+	}
+	for _, turn := range mapsOf(req.Input["history"]) {
+		role, _ := turn["role"].(string)
+		text, _ := turn["text"].(string)
+		if role != "" {
+			messages = append(messages, openAIMessage{Role: role, Content: text})
+		}
+	}
+	if raw, ok := req.Input["messages"].([]any); ok {
+		messages = append(messages, raw...)
+	}
+	if s, ok := req.Input["text"].(string); ok && s != "" {
+		messages = append(messages, openAIMessage{Role: "user", Content: s})
+	}
+	if results := mapsOf(req.Input["tool_results"]); len(results) > 0 {
+		assistant := openAIMessage{Role: "assistant"}
+		var replies []any
+		for _, result := range results {
+			call := openAIToolCall{Type: "function"}
+			call.ID, _ = result["tool_call_id"].(string)
+			call.Function.Name, _ = result["name"].(string)
+			call.Function.Arguments = jsonString(result["arguments"], "{}")
+			assistant.ToolCalls = append(assistant.ToolCalls, call)
+
+			content := result["output"]
+			if e, ok := result["error"]; ok {
+				content = map[string]any{"error": e}
+			}
+			replies = append(replies, openAIMessage{Role: "tool", ToolCallID: call.ID, Content: jsonString(content, "null")})
+		}
+		messages = append(messages, assistant)
+		messages = append(messages, replies...)
+	}
+
+	body := map[string]any{"model": p.model, "messages": messages}
+	if tools := mapsOf(req.Input["tools"]); len(tools) > 0 {
+		defs := make([]any, 0, len(tools))
+		for _, tool := range tools {
+			fn := map[string]any{"name": tool["name"]}
+			if d, ok := tool["description"]; ok {
+				fn["description"] = d
+			}
+			if schema, ok := tool["input_schema"]; ok {
+				fn["parameters"] = schema
+			} else {
+				fn["parameters"] = map[string]any{"type": "object"}
+			}
+			defs = append(defs, map[string]any{"type": "function", "function": fn})
+		}
+		body["tools"] = defs
+	}
+	for _, key := range openAIOptions {
+		if v, ok := req.Options[key]; ok {
+			body[key] = v
+		}
+	}
+	return body
+}
+
+// mapsOf returns the objects of a JSON array, as decoded from an invoke request.
+func mapsOf(v any) []map[string]any {
+	raw, _ := v.([]any)
+	out := make([]map[string]any, 0, len(raw))
+	for _, item := range raw {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func jsonString(v any, def string) string {
+	if v == nil {
+		return def
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return def
+	}
+	return string(b)
+}
+
+// upstreamErrorMessage extracts error.message from an OpenAI-style error body.
+func upstreamErrorMessage(raw []byte) string {
+	var decoded struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err == nil && decoded.Error.Message != "" {
+		return decoded.Error.Message
+	}
+	msg := strings.TrimSpace(string(raw))
+	if len(msg) > 200 {
+		msg = msg[:200]
+	}
+	return msg
+}
@@ -0,0 +1,120 @@
+package modelpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+// newFakeOpenAI serves chat completions with handler and registers it as the models
+// gpt-test (upstream name upstream-model) and gpt-other.
+func newFakeOpenAI(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	fake := httptest.NewServer(handler)
+	t.Cleanup(fake.Close)
+	t.Setenv("AGENTOS_OPENAI_BASE_URL", fake.URL+"/v1")
+	t.Setenv("AGENTOS_OPENAI_MODELS", "gpt-test=upstream-model, gpt-other")
+	t.Setenv("AGENTOS_OPENAI_API_KEY", "sk-test")
+}
+
+func invokeModel(t *testing.T, s *Server, tenantID string, req types.ModelInvokeRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/v1/models:invoke", bytes.NewReader(payload))
+	httpReq.Header.Set("X-Tenant-Id", tenantID)
+	httpReq.Header.Set("X-Principal-Id", "usr_test")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httpReq)
+	return rec
+}
+
+func TestOpenAIProviderMapsRequestAndRecordsUsage(t *testing.T) {
+	var got map[string]any
+	newFakeOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":\"x\"}"}}]},"finish_reason":"tool_calls"}],
+			"usage":{"prompt_tokens":40,"completion_tokens":2,"total_tokens":42}}`))
+	})
+	s := newTestServer(t)
+
+	rec := invokeModel(t, s, "tnt_openai", types.ModelInvokeRequest{
+		Operation: "chat",
+		ModelID:   "gpt-test",
+		Input: map[string]any{
+			"instructions": "be brief",
+			"history":      []any{map[string]any{"role": "user", "text": "earlier"}, map[string]any{"role": "assistant", "text": "reply"}},
+			"text":         "hello",
+			"tools":        []any{map[string]any{"name": "lookup", "kind": "http", "input_schema": map[string]any{"type": "object"}}},
+			"tool_results": []any{map[string]any{"tool_call_id": "call_0", "name": "lookup", "arguments": map[string]any{"q": "y"}, "output": map[string]any{"hits": 1}}},
+		},
+		Options: map[string]any{"temperature": 0.2, "max_tokens": 64, "deny": false},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	if got["model"] != "upstream-model" || got["temperature"] != 0.2 || got["max_tokens"] != float64(64) || got["deny"] != nil {
+		t.Fatalf("unexpected upstream request %v", got)
+	}
+	messages, _ := got["messages"].([]any)
+	roles := []string{}
+	for _, m := range messages {
+		roles = append(roles, m.(map[string]any)["role"].(string))
+	}
+	if order, want := strings.Join(roles, ","), "system,user,assistant,user,assistant,tool"; order != want {
+		t.Fatalf("expected roles %s, got %s", want, order)
+	}
+	toolCall := messages[4].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)["function"].(map[string]any)
+	if toolCall["arguments"] != `{"q":"y"}` {
+		t.Fatalf("expected the previous call's arguments, got %v", toolCall)
+	}
+	if tools, _ := got["tools"].([]any); len(tools) != 1 {
+		t.Fatalf("expected one tool definition, got %v", got["tools"])
+	}
+
+	var resp types.ModelInvokeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if calls, _ := resp.Output["tool_calls"].([]any); len(calls) != 1 || resp.Output["finish_reason"] != "tool_calls" {
+		t.Fatalf("expected the tool call in the output, got %v", resp.Output)
+	}
+	if resp.Usage["total_tokens"] != float64(42) || resp.Usage["provider"] != "openai" {
+		t.Fatalf("expected upstream usage, got %v", resp.Usage)
+	}
+	if hourly, daily := s.usage.GetUsage("tnt_openai"); hourly != 42 || daily != 42 {
+		t.Fatalf("expected 42 tokens metered, got %d/%d", hourly, daily)
+	}
+}
+
+func TestOpenAIProviderErrorsReturn502(t *testing.T) {
+	newFakeOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":{"message":"model overloaded"}}`))
+	})
+	s := newTestServer(t)
+
+	rec := invokeModel(t, s, "tnt_openai", types.ModelInvokeRequest{Operation: "chat", ModelID: "gpt-other", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusBadGateway || !bytes.Contains(rec.Body.Bytes(), []byte("model overloaded")) {
+		t.Fatalf("expected 502 with the upstream message, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if hourly, _ := s.usage.GetUsage("tnt_openai"); hourly != 0 {
+		t.Fatalf("expected no usage for a failed call, got %d", hourly)
+	}
+}
+
+func TestRegisterOpenAIFromEnvRequiresModels(t *testing.T) {
+	t.Setenv("AGENTOS_OPENAI_BASE_URL", "http://localhost:1/v1")
+	t.Setenv("AGENTOS_OPENAI_MODELS", " , ")
+	if err := registerOpenAIFromEnv(newRegistry()); err == nil {
+		t.Fatalf("expected an error without models")
+	}
+}
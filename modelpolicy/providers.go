@@ -1,7 +1,10 @@
 package modelpolicy
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -9,68 +12,193 @@ import (
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
 )
 
+// provider invokes a model and returns its output and usage. Usage must include
+// total_tokens for token budgets to apply.
 type provider interface {
-	Invoke(req types.ModelInvokeRequest) (map[string]any, map[string]any, error)
+	Invoke(ctx context.Context, req types.ModelInvokeRequest) (map[string]any, map[string]any, error)
+	// Stream invokes a model like Invoke, passing each increment of the output to emit
+	// as it is generated (text deltas as {"text": ...}). An emit error aborts the call;
+	// on any error the usage consumed so far is returned when known.
+	Stream(ctx context.Context, req types.ModelInvokeRequest, emit func(delta map[string]any) error) (map[string]any, map[string]any, error)
 }
 
+// defaultAlias names the model used when a request does not pick one and, outside
+// strict mode, in place of unknown models.
+const defaultAlias = "default"
+
 type providerEntry struct {
-	model    types.Model
-	impl     provider
-	defaults bool
+	model   types.Model
+	impl    provider
+	circuit string // circuit breaker key, see circuitKey
 }
 
 type registry struct {
 	mu    sync.RWMutex
-	model map[string]providerEntry
+	model map[string]providerEntry // built-in and environment-configured models
+	// catalog holds the models of the catalog file, which replace built-in models of the
+	// same ID; nil entries are disabled models, hidden even if built in.
+	catalog map[string]*providerEntry
+	// aliases map names such as default, fast or smart to model IDs. The catalog's
+	// aliases replace the built-in ones; tenant policies can override both.
+	aliases        map[string]string
+	catalogAliases map[string]string
+	// routes split requests for a name across models by weight; fallbacks list, per
+	// model, the models tried in order when its provider fails. Both come from the catalog.
+	routes    map[string][]weightedTarget
+	fallbacks map[string][]string
+	breakers  *breakers
+	intn      func(n int) int // picks weighted targets
+	// catalogSum is the checksum of the loaded catalog file and its credentials, to skip
+	// unchanged reloads; rejectedSum is that of the last file that failed to load.
+	catalogSum  [32]byte
+	rejectedSum [32]byte
+	// strict rejects unknown models instead of running the default model in their place.
+	strict bool
 }
 
 func newRegistry() *registry {
 	r := &registry{
-		model: make(map[string]providerEntry),
+		model:    make(map[string]providerEntry),
+		catalog:  make(map[string]*providerEntry),
+		aliases:  map[string]string{defaultAlias: "local-stub-llm"},
+		strict:   true,
+		breakers: newBreakers(5, 30*time.Second),
+		intn:     rand.Intn,
 	}
 	r.register(types.Model{
 		ModelID:      "local-stub-llm",
 		Provider:     "stub",
 		DisplayName:  "Local Stub LLM",
 		Capabilities: map[string]any{"chat": true},
-	}, stubProvider{}, true)
+	}, stubProvider{})
 	return r
 }
 
-func (r *registry) register(model types.Model, impl provider, defaults bool) {
+func (r *registry) register(model types.Model, impl provider) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.model[model.ModelID] = providerEntry{model: model, impl: impl, defaults: defaults}
+	r.model[model.ModelID] = providerEntry{model: model, impl: impl, circuit: circuitKey(model.Provider, impl)}
+}
+
+// entriesLocked merges the built-in models with the catalog.
+func (r *registry) entriesLocked() map[string]providerEntry {
+	out := make(map[string]providerEntry, len(r.model)+len(r.catalog))
+	for id, entry := range r.model {
+		out[id] = entry
+	}
+	for id, entry := range r.catalog {
+		if entry == nil {
+			delete(out, id)
+			continue
+		}
+		out[id] = *entry
+	}
+	return out
 }
 
+// lookupLocked returns the enabled model modelID; disabled reports a model the catalog
+// disabled.
+func (r *registry) lookupLocked(modelID string) (entry providerEntry, ok, disabled bool) {
+	if e, found := r.catalog[modelID]; found {
+		if e == nil {
+			return providerEntry{}, false, true
+		}
+		return *e, true, false
+	}
+	e, ok := r.model[modelID]
+	return e, ok, false
+}
+
+// Models returns the enabled models ordered by model ID.
 func (r *registry) Models() []types.Model {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-	out := make([]types.Model, 0, len(r.model))
-	for _, entry := range r.model {
+	entries := r.entriesLocked()
+	r.mu.RUnlock()
+	out := make([]types.Model, 0, len(entries))
+	for _, entry := range entries {
 		out = append(out, entry.model)
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ModelID < out[j].ModelID })
+	return out
+}
+
+// Aliases returns the aliases in effect for a tenant with the given policy aliases.
+func (r *registry) Aliases(tenantAliases map[string]string) map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]string, len(r.aliases)+len(r.catalogAliases)+len(tenantAliases))
+	for _, aliases := range []map[string]string{r.aliases, r.catalogAliases, tenantAliases} {
+		for name, target := range aliases {
+			out[name] = target
+		}
+	}
 	return out
 }
 
-func (r *registry) Resolve(modelID string) (provider, types.Model, bool) {
+func (r *registry) aliasLocked(name string, tenantAliases map[string]string) string {
+	for _, aliases := range []map[string]string{tenantAliases, r.catalogAliases, r.aliases} {
+		if target, ok := aliases[name]; ok {
+			return target
+		}
+	}
+	return name
+}
+
+// Resolve returns the route a request for modelID runs on. An alias (tenant aliases
+// first) maps to a model or a weighted route, and an empty modelID means the default
+// alias. In strict mode an unknown model is not found; otherwise the default model runs
+// in its place, which callers must report as the model that ran. Disabled models are
+// never replaced.
+func (r *registry) Resolve(modelID string, tenantAliases map[string]string) (route, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	if entry, ok := r.model[modelID]; ok {
-		return entry.impl, entry.model, true
+	if modelID == "" {
+		modelID = defaultAlias
+	}
+	entry, ok, disabled := r.lookupLocked(r.targetLocked(modelID, tenantAliases))
+	if !ok {
+		if r.strict || disabled {
+			return nil, false
+		}
+		if entry, ok, _ = r.lookupLocked(r.targetLocked(defaultAlias, tenantAliases)); !ok {
+			return nil, false
+		}
 	}
-	// fall back to default provider if requested model missing
-	for _, entry := range r.model {
-		if entry.defaults {
-			return entry.impl, entry.model, true
+	rt := route{entry}
+	seen := map[string]bool{entry.model.ModelID: true}
+	for _, id := range r.fallbacks[entry.model.ModelID] {
+		if fallback, ok, _ := r.lookupLocked(id); ok && !seen[id] {
+			rt = append(rt, fallback)
+			seen[id] = true
 		}
 	}
-	return nil, types.Model{}, false
+	return rt, true
+}
+
+// targetLocked resolves an alias, then picks a model of a weighted route.
+func (r *registry) targetLocked(name string, tenantAliases map[string]string) string {
+	name = r.aliasLocked(name, tenantAliases)
+	targets, ok := r.routes[name]
+	if !ok {
+		return name
+	}
+	total := 0
+	for _, t := range targets {
+		total += t.Weight
+	}
+	n := r.intn(total)
+	for _, t := range targets {
+		if n < t.Weight {
+			return t.ModelID
+		}
+		n -= t.Weight
+	}
+	return targets[len(targets)-1].ModelID
 }
 
 type stubProvider struct{}
 
-func (stubProvider) Invoke(req types.ModelInvokeRequest) (map[string]any, map[string]any, error) {
+func (stubProvider) Invoke(_ context.Context, req types.ModelInvokeRequest) (map[string]any, map[string]any, error) {
 	text := "stub response"
 	if inputText, ok := req.Input["text"].(string); ok && strings.TrimSpace(inputText) != "" {
 		text = fmt.Sprintf("stub: %s", inputText)
@@ -96,6 +224,21 @@ func (stubProvider) Invoke(req types.ModelInvokeRequest) (map[string]any, map[st
 	return output, usage, nil
 }
 
+// Stream emits the stub response word by word.
+func (p stubProvider) Stream(ctx context.Context, req types.ModelInvokeRequest, emit func(map[string]any) error) (map[string]any, map[string]any, error) {
+	output, usage, err := p.Invoke(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	text, _ := output["text"].(string)
+	for _, word := range strings.SplitAfter(text, " ") {
+		if err := emit(map[string]any{"text": word}); err != nil {
+			return nil, usage, err
+		}
+	}
+	return output, usage, nil
+}
+
 func tokenEstimate(input map[string]any) int {
 	if input == nil {
 		return 8
@@ -0,0 +1,165 @@
+package modelpolicy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned when every model of a route is behind an open circuit.
+var errCircuitOpen = errors.New("no provider available: circuit open")
+
+// route lists the models a request may run on, in order: the model it resolved to, then
+// that model's fallbacks.
+type route []providerEntry
+
+// weightedTarget is one model of a weighted route, e.g. for A/B tests.
+type weightedTarget struct {
+	ModelID string `json:"model_id"`
+	Weight  int    `json:"weight"`
+}
+
+// noFailover marks a provider error after which the call must not move on to a fallback,
+// e.g. because output was already streamed to the client.
+type noFailover struct{ error }
+
+func (e noFailover) Unwrap() error { return e.error }
+
+// call runs fn on the models of rt in order until one succeeds and returns the model
+// that served the call. Models whose provider circuit is open are skipped, and every
+// outcome feeds the circuit. Errors caused by ctx ending are not held against the
+// provider and end the call, as do requests the provider rejected (see
+// providerStatusError.rejected): the provider is up, and a fallback would reject them too.
+func (r *registry) call(ctx context.Context, rt route, fn func(providerEntry) error) (providerEntry, error) {
+	var lastErr error
+	for _, entry := range rt {
+		if !r.breakers.allow(entry.circuit) {
+			continue
+		}
+		err := fn(entry)
+		if err == nil {
+			r.breakers.success(entry.circuit)
+			return entry, nil
+		}
+		if ctx.Err() != nil {
+			r.breakers.abandon(entry.circuit)
+			return entry, err
+		}
+		var status *providerStatusError
+		if errors.As(err, &status) && status.rejected() {
+			r.breakers.success(entry.circuit)
+			return entry, err
+		}
+		r.breakers.failure(entry.circuit)
+		if errors.As(err, &noFailover{}) {
+			return entry, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return providerEntry{}, errCircuitOpen
+	}
+	return providerEntry{}, lastErr
+}
+
+// circuitKey identifies the upstream a provider calls, so models served by the same
+// server share a circuit.
+func circuitKey(provider string, impl provider) string {
+	if p, ok := impl.(*openAIProvider); ok {
+		return "openai:" + p.baseURL
+	}
+	return provider
+}
+
+// breakers are per-provider circuit breakers. A circuit opens after threshold
+// consecutive failures and rejects calls for cooldown; then one call is let through,
+// which closes the circuit on success or reopens it on failure.
+type breakers struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	circuits  map[string]*circuit
+}
+
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool // a half-open probe is in flight
+}
+
+func newBreakers(threshold int, cooldown time.Duration) *breakers {
+	return &breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+// breakersFromEnv reads AGENTOS_MODELS_BREAKER_FAILURES (default 5) and
+// AGENTOS_MODELS_BREAKER_COOLDOWN_MS (default 30000).
+func breakersFromEnv() *breakers {
+	threshold := 5
+	if v, err := strconv.Atoi(os.Getenv("AGENTOS_MODELS_BREAKER_FAILURES")); err == nil && v > 0 {
+		threshold = v
+	}
+	cooldown := 30000
+	if v, err := strconv.Atoi(os.Getenv("AGENTOS_MODELS_BREAKER_COOLDOWN_MS")); err == nil && v > 0 {
+		cooldown = v
+	}
+	return newBreakers(threshold, time.Duration(cooldown)*time.Millisecond)
+}
+
+// allow reports whether a call to key may proceed.
+func (b *breakers) allow(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok || c.failures < b.threshold {
+		return true
+	}
+	now := b.now()
+	if now.Before(c.openUntil) {
+		return false
+	}
+	// half-open: let this call probe the provider and hold off others until it reports
+	c.openUntil = now.Add(b.cooldown)
+	c.probing = true
+	return true
+}
+
+// abandon ends a probe of key that finished without an outcome, such as one whose caller
+// went away, so the next call probes again instead of waiting out another cooldown.
+func (b *breakers) abandon(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[key]; ok && c.probing {
+		c.probing = false
+		c.openUntil = b.now()
+	}
+}
+
+func (b *breakers) success(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, key)
+}
+
+func (b *breakers) failure(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	c.failures++
+	c.probing = false
+	if c.failures >= b.threshold {
+		c.openUntil = b.now().Add(b.cooldown)
+	}
+}
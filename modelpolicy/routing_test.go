@@ -0,0 +1,181 @@
+package modelpolicy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/secrets"
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+func TestBreakerTripsAndRecoversAfterCooldown(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreakers(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure("p")
+	if !b.allow("p") {
+		t.Fatalf("expected the circuit closed below the threshold")
+	}
+	b.failure("p")
+	if b.allow("p") || !b.allow("other") {
+		t.Fatalf("expected only the failing provider's circuit open")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow("p") {
+		t.Fatalf("expected a probe after the cooldown")
+	}
+	if b.allow("p") {
+		t.Fatalf("expected other calls held off while probing")
+	}
+	b.failure("p")
+	now = now.Add(time.Minute)
+	if !b.allow("p") {
+		t.Fatalf("expected another probe after the next cooldown")
+	}
+	b.abandon("p")
+	if !b.allow("p") {
+		t.Fatalf("expected an abandoned probe to let the next call probe")
+	}
+	if b.allow("p") {
+		t.Fatalf("expected other calls held off while probing again")
+	}
+	b.success("p")
+	if !b.allow("p") || !b.allow("p") {
+		t.Fatalf("expected the circuit closed after a successful probe")
+	}
+}
+
+func TestWeightedRouteSplitsByWeight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[{"model_id":"stub-a","provider":"stub"},{"model_id":"stub-b","provider":"stub"}],
+		"routes":{"chat-ab":[{"model_id":"stub-a","weight":1},{"model_id":"stub-b","weight":3}]},
+		"aliases":{"smart":"chat-ab"}}`)
+	r := newRegistry()
+	if _, err := r.loadCatalog(path, secrets.NewLoader()); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	for n, want := range []string{"stub-a", "stub-b", "stub-b", "stub-b"} {
+		r.intn = func(total int) int {
+			if total != 4 {
+				t.Fatalf("expected the total weight 4, got %d", total)
+			}
+			return n
+		}
+		rt, ok := r.Resolve("smart", nil)
+		if !ok || rt[0].model.ModelID != want {
+			t.Fatalf("draw %d: expected %s, got %+v", n, want, rt)
+		}
+	}
+
+	writeModelsFile(t, path, `{"models":[{"model_id":"stub-a","provider":"stub"}],"routes":{"chat-ab":[{"model_id":"stub-a","weight":0}]}}`)
+	if _, err := r.loadCatalog(path, secrets.NewLoader()); err == nil {
+		t.Fatalf("expected an error for a route without weight")
+	}
+}
+
+func TestInvokeFailsOverAndOpensCircuit(t *testing.T) {
+	var hits atomic.Int32
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(fake.Close)
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[
+		{"model_id":"primary","provider":"openai","endpoint":"`+fake.URL+`/v1"},
+		{"model_id":"lonely","provider":"openai","endpoint":"`+fake.URL+`/v1"}],
+		"fallbacks":{"primary":["local-stub-llm"]}}`)
+	t.Setenv("AGENTOS_MODELS_FILE", path)
+	t.Setenv("AGENTOS_MODELS_BREAKER_FAILURES", "2")
+	s := newTestServer(t)
+	req := types.ModelInvokeRequest{Operation: "chat", ModelID: "primary", Input: map[string]any{"text": "hi"}}
+
+	for i := 0; i < 3; i++ {
+		rec := invokeModel(t, s, "tnt_failover", req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("call %d: expected the fallback to serve, got %d body=%s", i, rec.Code, rec.Body.String())
+		}
+		if resp := decodeInvoke(t, rec); resp.ModelID != "local-stub-llm" {
+			t.Fatalf("call %d: expected the fallback reported, got %s", i, resp.ModelID)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected the open circuit to skip the provider, got %d upstream calls", hits.Load())
+	}
+
+	// models of the same server share its circuit
+	rec := invokeModel(t, s, "tnt_failover", types.ModelInvokeRequest{Operation: "chat", ModelID: "lonely", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 503 with Retry-After, got %d %q body=%s", rec.Code, rec.Header().Get("Retry-After"), rec.Body.String())
+	}
+
+	// fallbacks the tenant may not use are skipped
+	if err := s.tenants.Create(types.Tenant{TenantID: "tnt_nostub", Policy: &types.TenantPolicy{DeniedModels: []string{"local-stub-llm"}}}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	if rec := invokeModel(t, s, "tnt_nostub", req); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected no fallback for the tenant, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStreamFailsOverBeforeFirstDelta(t *testing.T) {
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(fake.Close)
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[{"model_id":"primary","provider":"openai","endpoint":"`+fake.URL+`/v1"}],
+		"fallbacks":{"primary":["local-stub-llm"]}}`)
+	t.Setenv("AGENTOS_MODELS_FILE", path)
+	s := newTestServer(t)
+
+	rec, events := streamModel(t, s, "tnt_stream_failover", types.ModelInvokeRequest{Operation: "chat", ModelID: "primary", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if text, done := streamResult(t, events); text != "stub: hi" || done.ModelID != "local-stub-llm" {
+		t.Fatalf("expected the fallback's stream, got %q %+v", text, done)
+	}
+}
+
+func TestRejectedRequestsDoNotFailOverOrTripCircuit(t *testing.T) {
+	var hits atomic.Int32
+	status := http.StatusBadRequest
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":{"message":"context length exceeded"}}`))
+	}))
+	t.Cleanup(fake.Close)
+	path := filepath.Join(t.TempDir(), "models.json")
+	writeModelsFile(t, path, `{"models":[{"model_id":"primary","provider":"openai","endpoint":"`+fake.URL+`/v1"}],
+		"fallbacks":{"primary":["local-stub-llm"]}}`)
+	t.Setenv("AGENTOS_MODELS_FILE", path)
+	t.Setenv("AGENTOS_MODELS_BREAKER_FAILURES", "1")
+	s := newTestServer(t)
+	req := types.ModelInvokeRequest{Operation: "chat", ModelID: "primary", Input: map[string]any{"text": "hi"}}
+
+	for i := 0; i < 3; i++ {
+		rec := invokeModel(t, s, "tnt_rejected", req)
+		if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "context length exceeded") {
+			t.Fatalf("call %d: expected the provider's rejection without failover, got %d body=%s", i, rec.Code, rec.Body.String())
+		}
+	}
+	if hits.Load() != 3 {
+		t.Fatalf("expected rejections to leave the circuit closed, got %d upstream calls", hits.Load())
+	}
+
+	// rate limiting is the provider failing to serve, so the fallback answers
+	status = http.StatusTooManyRequests
+	if resp := decodeInvoke(t, invokeModel(t, s, "tnt_rejected", req)); resp.ModelID != "local-stub-llm" {
+		t.Fatalf("expected the fallback to serve after a 429, got %s", resp.ModelID)
+	}
+}
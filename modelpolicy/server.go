@@ -1,9 +1,11 @@
 package modelpolicy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/audit"
@@ -18,28 +20,61 @@ import (
 
 type Server struct {
 	version   string
-	limiter   *quota.Limiter
+	limiter   quota.Limiter
 	audit     audit.Logger
 	providers *registry
 	policy    *policyEngine
 	usage     *usageMeter
 	tenants   *tenants.Store
+	models    string // models file reloaded by Start; empty without a catalog
 }
 
-func New(version string) *Server {
-	tenantStore := tenants.NewStore()
-	if def := auth.DefaultTenant(); def != "" {
-		tenantStore.EnsureDefault(def)
+func New(version string) (*Server, error) {
+	tenantStore, err := tenants.NewStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := tenantStore.EnsureDefault(auth.DefaultTenant()); err != nil {
+		return nil, err
+	}
+	providers := newRegistry()
+	// AGENTOS_MODELS_STRICT=0 runs unknown models on the default model instead of
+	// rejecting them; the invoke response still reports the model that ran.
+	providers.strict = strings.TrimSpace(os.Getenv("AGENTOS_MODELS_STRICT")) != "0"
+	providers.breakers = breakersFromEnv()
+	if err := registerOpenAIFromEnv(providers); err != nil {
+		return nil, err
+	}
+	modelsFile, err := catalogFromEnv(providers)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := quota.TenantLimitsFromEnv(tenantStore.Get, quota.InvokeQuotaKeys, "AGENTOS_QUOTA_INVOKE_QPS", "AGENTOS_QUOTA_UNUSED_CONCURRENT", 20, 999999)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := quota.NewFromEnv("model-policy", limits)
+	if err != nil {
+		return nil, err
 	}
 	return &Server{
 		version:   version,
-		limiter:   quota.NewFromEnv("AGENTOS_QUOTA_INVOKE_QPS", "AGENTOS_QUOTA_UNUSED_CONCURRENT", 20, 999999),
+		limiter:   limiter,
 		audit:     audit.NewFromEnv(),
-		providers: newRegistry(),
+		providers: providers,
 		policy:    newPolicyEngine(),
 		usage:     newUsageMeter(),
 		tenants:   tenantStore,
-	}
+		models:    modelsFile,
+	}, nil
+}
+
+// Start launches the tenant store refresh, so tenants written by other services apply
+// here, and the model catalog reload. It returns immediately; both stop when ctx is
+// canceled.
+func (s *Server) Start(ctx context.Context) {
+	s.tenants.WatchFromEnv(ctx)
+	s.providers.watchCatalog(ctx, s.models, catalogPollInterval())
 }
 
 func (s *Server) Handler() http.Handler {
@@ -47,6 +82,7 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/v1/health", s.handleHealth)
 	mux.HandleFunc("/v1/models", s.handleModels)
 	mux.HandleFunc("/v1/models:invoke", s.handleInvoke)
+	mux.HandleFunc("/v1/models:stream", s.handleStream)
 	mux.HandleFunc("/v1/policy:check", s.handlePolicyCheck)
 	mux.Handle("/metrics", middleware.ProtectMetrics(metrics.Handler()))
 
@@ -69,22 +105,80 @@ func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
 		return
 	}
-	resp := types.ModelsListResponse{Models: s.providers.Models()}
+	var aliases map[string]string
+	if ac, _ := auth.Get(r.Context()); ac.TenantID != "" {
+		if tenant, ok := s.tenants.Get(ac.TenantID); ok && tenant.Policy != nil {
+			aliases = tenant.Policy.ModelAliases
+		}
+	}
+	resp := types.ModelsListResponse{Models: s.providers.Models(), Aliases: s.providers.Aliases(aliases)}
 	httpx.JSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
+	call, ok := s.admitInvoke(w, r)
+	if !ok {
+		return
+	}
+
+	var output, usage map[string]any
+	served, err := s.providers.call(r.Context(), call.route, func(entry providerEntry) error {
+		var err error
+		output, usage, err = entry.impl.Invoke(r.Context(), call.requestFor(entry))
+		return err
+	})
+	if err != nil {
+		s.providerError(w, r, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, s.finishInvoke(r, call, served, output, usage))
+}
+
+// providerError writes the response for a call that no model of its route served.
+func (s *Server) providerError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errCircuitOpen) {
+		quota.SetRetryAfter(w.Header(), s.providers.breakers.cooldown)
+		httpx.Error(w, http.StatusServiceUnavailable, "provider_unavailable", err.Error(), httpx.CorrelationID(r), true)
+		return
+	}
+	var status *providerStatusError
+	retryable := !errors.As(err, &status) || !status.rejected()
+	httpx.Error(w, http.StatusBadGateway, "provider_error", err.Error(), httpx.CorrelationID(r), retryable)
+}
+
+// invokeCall is an invoke request that passed the quota, policy and budget checks.
+type invokeCall struct {
+	tenantID  string
+	ac        auth.AuthContext
+	req       types.ModelInvokeRequest
+	requested string // model ID or alias as requested
+	route     route  // models allowed to serve the call, in order
+}
+
+// requestFor returns the request as sent to the model of entry.
+func (c invokeCall) requestFor(entry providerEntry) types.ModelInvokeRequest {
+	req := c.req
+	req.ModelID = entry.model.ModelID
+	return req
+}
+
+// admitInvoke decodes an invoke request and applies the QPS quota, model resolution,
+// tenant policy and token budget. On failure it writes the error response and returns
+// false.
+func (s *Server) admitInvoke(w http.ResponseWriter, r *http.Request) (invokeCall, bool) {
 	if r.Method != http.MethodPost {
 		httpx.Error(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", httpx.CorrelationID(r), false)
-		return
+		return invokeCall{}, false
 	}
 
 	ac, _ := auth.Get(r.Context())
 	tenantID, ok := resolveTenant(w, r, ac)
 	if !ok {
-		return
+		return invokeCall{}, false
 	}
-	if !s.limiter.AllowQPS(tenantID) {
+	qps := s.limiter.AllowQPS(tenantID)
+	quota.SetHeaders(w.Header(), qps)
+	if !qps.Allowed {
 		metrics.IncQuotaDenied("model-policy", "models_invoke_qps")
 		httpx.Error(w, http.StatusTooManyRequests, "quota_exceeded", "invoke QPS exceeded", httpx.CorrelationID(r), true)
 		s.audit.Log(audit.Entry{
@@ -92,13 +186,13 @@ func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
 			CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
 			Meta: map[string]any{"reason": "qps_exceeded"},
 		})
-		return
+		return invokeCall{}, false
 	}
 
 	var req types.ModelInvokeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		httpx.Error(w, http.StatusBadRequest, "invalid_json", "invalid json body", httpx.CorrelationID(r), false)
-		return
+		return invokeCall{}, false
 	}
 
 	// Look up tenant policy
@@ -107,6 +201,19 @@ func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
 		policy = tenant.Policy
 	}
 
+	// Resolve aliases, so policy applies to the model that will run
+	var aliases map[string]string
+	if policy != nil {
+		aliases = policy.ModelAliases
+	}
+	rt, ok := s.providers.Resolve(req.ModelID, aliases)
+	if !ok {
+		httpx.Error(w, http.StatusNotFound, "model_not_found", "model not found: "+req.ModelID, httpx.CorrelationID(r), false)
+		return invokeCall{}, false
+	}
+	requested := req.ModelID
+	req.ModelID = rt[0].model.ModelID
+
 	// Check model allow/deny policy
 	decision, reasons := s.policy.Evaluate(tenantID, ac, req, policy)
 	if decision != "allow" {
@@ -116,7 +223,7 @@ func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
 			CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
 			Meta: map[string]any{"policy_reasons": reasons},
 		})
-		return
+		return invokeCall{}, false
 	}
 
 	// Check token budget
@@ -129,36 +236,43 @@ func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
 				CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
 				Meta: map[string]any{"policy_reasons": []string{reason}},
 			})
-			return
+			return invokeCall{}, false
 		}
 	}
 
-	prov, model, ok := s.providers.Resolve(req.ModelID)
-	if !ok {
-		httpx.Error(w, http.StatusNotFound, "model_not_found", "model not found", httpx.CorrelationID(r), false)
-		return
+	// fallbacks are subject to the same allow/deny lists
+	allowed := rt[:1]
+	for _, entry := range rt[1:] {
+		fallback := req
+		fallback.ModelID = entry.model.ModelID
+		if decision, _ := s.policy.Evaluate(tenantID, ac, fallback, policy); decision == "allow" {
+			allowed = append(allowed, entry)
+		}
 	}
 
-	output, usage, err := prov.Invoke(req)
-	if err != nil {
-		httpx.Error(w, http.StatusBadGateway, "provider_error", err.Error(), httpx.CorrelationID(r), true)
-		return
-	}
-	s.usage.Record(tenantID, usage)
+	return invokeCall{tenantID: tenantID, ac: ac, req: req, requested: requested, route: allowed}, true
+}
 
-	resp := types.ModelInvokeResponse{
+// finishInvoke meters and audits a call served by the model of served and returns its
+// response.
+func (s *Server) finishInvoke(r *http.Request, call invokeCall, served providerEntry, output, usage map[string]any) types.ModelInvokeResponse {
+	model := served.model
+	s.usage.Record(call.tenantID, usage)
+	s.audit.Log(audit.Entry{
+		TenantID: call.tenantID, PrincipalID: call.ac.PrincipalID, Action: "models.invoke", Resource: "model/" + model.ModelID, Outcome: "allowed",
+		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
+		Meta: map[string]any{
+			"operation": call.req.Operation, "model_id": model.ModelID, "requested_model_id": call.requested, "provider": model.Provider,
+			"fallback_used": model.ModelID != call.req.ModelID,
+		},
+	})
+	return types.ModelInvokeResponse{
+		ModelID:       model.ModelID,
+		Provider:      model.Provider,
 		Output:        output,
 		Usage:         usage,
 		CorrelationID: httpx.CorrelationID(r),
 	}
-
-	s.audit.Log(audit.Entry{
-		TenantID: tenantID, PrincipalID: ac.PrincipalID, Action: "models.invoke", Resource: "model/" + req.ModelID, Outcome: "allowed",
-		CorrelationID: httpx.CorrelationID(r), RequestID: r.Header.Get("X-Request-Id"),
-		Meta: map[string]any{"operation": req.Operation, "model_id": model.ModelID, "provider": model.Provider},
-	})
-
-	httpx.JSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handlePolicyCheck(w http.ResponseWriter, r *http.Request) {
@@ -2,16 +2,21 @@ package modelpolicy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/tenants"
 	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
 )
 
 func TestInvokePolicyDeniedWhenOptionDeny(t *testing.T) {
-	s := New("test")
+	s := newTestServer(t)
 	rec := httptest.NewRecorder()
 	reqBody := types.ModelInvokeRequest{
 		Operation: "chat",
@@ -32,7 +37,7 @@ func TestInvokePolicyDeniedWhenOptionDeny(t *testing.T) {
 }
 
 func TestInvokeReturnsUsage(t *testing.T) {
-	s := New("test")
+	s := newTestServer(t)
 	rec := httptest.NewRecorder()
 	reqBody := types.ModelInvokeRequest{
 		Operation: "chat",
@@ -60,7 +65,7 @@ func TestInvokeReturnsUsage(t *testing.T) {
 }
 
 func TestInvokeDeniedModelReturns403(t *testing.T) {
-	s := New("test")
+	s := newTestServer(t)
 
 	// Create tenant with denied model policy
 	tenant := types.Tenant{
@@ -91,7 +96,7 @@ func TestInvokeDeniedModelReturns403(t *testing.T) {
 }
 
 func TestInvokeModelNotInAllowListReturns403(t *testing.T) {
-	s := New("test")
+	s := newTestServer(t)
 
 	// Create tenant with allowed model policy that doesn't include the requested model
 	tenant := types.Tenant{
@@ -122,7 +127,7 @@ func TestInvokeModelNotInAllowListReturns403(t *testing.T) {
 }
 
 func TestInvokeExceedingTokenBudgetReturns403(t *testing.T) {
-	s := New("test")
+	s := newTestServer(t)
 
 	// Create tenant with very low token budget
 	tenant := types.Tenant{
@@ -170,7 +175,7 @@ func TestInvokeExceedingTokenBudgetReturns403(t *testing.T) {
 }
 
 func TestInvokeAllowedModelWithBudgetSucceeds(t *testing.T) {
-	s := New("test")
+	s := newTestServer(t)
 
 	// Create tenant with policy that allows the model and has high budget
 	tenant := types.Tenant{
@@ -203,3 +208,151 @@ func TestInvokeAllowedModelWithBudgetSucceeds(t *testing.T) {
 		t.Fatalf("expected 200 for allowed model with budget, got %d body=%s", rec.Code, rec.Body.String())
 	}
 }
+
+func TestInvokeQuotaDenialSetsRateLimitHeaders(t *testing.T) {
+	t.Setenv("AGENTOS_QUOTA_INVOKE_QPS", "1")
+	s := newTestServer(t)
+	invoke := func() *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(types.ModelInvokeRequest{Operation: "chat", ModelID: "local-stub-llm", Input: map[string]any{"text": "hello"}})
+		req := httptest.NewRequest(http.MethodPost, "/v1/models:invoke", bytes.NewReader(payload))
+		req.Header.Set("X-Tenant-Id", "tnt_rate")
+		req.Header.Set("X-Principal-Id", "usr_test")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := invoke()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("RateLimit-Limit") != "1" || rec.Header().Get("RateLimit-Remaining") != "0" || rec.Header().Get("Retry-After") != "" {
+		t.Fatalf("expected rate limit headers without Retry-After, got %v", rec.Header())
+	}
+
+	rec = invoke()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") != "1" || rec.Header().Get("RateLimit-Reset") != "1" {
+		t.Fatalf("expected a retry after one refill at 1 QPS, got %v", rec.Header())
+	}
+}
+
+func TestInvokeAppliesPolicyWrittenByAnotherService(t *testing.T) {
+	s := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	t.Setenv("AGENTOS_TENANT_STORE_POLL_MS", "10")
+	s.Start(ctx)
+
+	// the orchestrator's admin API writes to the same tenant store
+	orchestrator, err := tenants.NewStoreFromEnv()
+	if err != nil {
+		t.Fatalf("NewStoreFromEnv: %v", err)
+	}
+	if err := orchestrator.Create(types.Tenant{TenantID: "tnt_shared", Policy: &types.TenantPolicy{DeniedModels: []string{"local-stub-llm"}}}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		payload, _ := json.Marshal(types.ModelInvokeRequest{Operation: "chat", ModelID: "local-stub-llm", Input: map[string]any{"text": "hello"}})
+		req := httptest.NewRequest(http.MethodPost, "/v1/models:invoke", bytes.NewReader(payload))
+		req.Header.Set("X-Tenant-Id", "tnt_shared")
+		req.Header.Set("X-Principal-Id", "usr_test")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code == http.StatusForbidden {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the shared tenant policy to apply, got %d", rec.Code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AGENTOS_TENANT_STORE_FILE", filepath.Join(dir, "tenants.json"))
+	t.Setenv("AGENTOS_AUDIT_SINK", "file:"+filepath.Join(dir, "audit.log"))
+	s, err := New("test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func decodeInvoke(t *testing.T, rec *httptest.ResponseRecorder) types.ModelInvokeResponse {
+	t.Helper()
+	var resp types.ModelInvokeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v body=%s", err, rec.Body.String())
+	}
+	return resp
+}
+
+func TestInvokeUnknownModelReturnsNotFound(t *testing.T) {
+	s := newTestServer(t)
+	rec := invokeModel(t, s, "tnt_strict", types.ModelInvokeRequest{Operation: "chat", ModelID: "local-stub-lmm", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusNotFound || !bytes.Contains(rec.Body.Bytes(), []byte("model_not_found")) {
+		t.Fatalf("expected 404 model_not_found, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if hourly, _ := s.usage.GetUsage("tnt_strict"); hourly != 0 {
+		t.Fatalf("expected no usage for an unknown model, got %d", hourly)
+	}
+}
+
+func TestInvokeFallbackReportsResolvedModel(t *testing.T) {
+	t.Setenv("AGENTOS_MODELS_STRICT", "0")
+	s := newTestServer(t)
+	rec := invokeModel(t, s, "tnt_lenient", types.ModelInvokeRequest{Operation: "chat", ModelID: "typo-model", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	resp := decodeInvoke(t, rec)
+	if resp.ModelID != "local-stub-llm" || resp.Usage["model_id"] != "local-stub-llm" {
+		t.Fatalf("expected the default model reported, got model_id=%q usage=%v", resp.ModelID, resp.Usage)
+	}
+}
+
+func TestInvokeResolvesTenantAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, []byte(`{"models":[{"model_id":"stub-fast","provider":"stub"},{"model_id":"stub-smart","provider":"stub"}],
+		"aliases":{"fast":"stub-fast","smart":"stub-smart"}}`), 0o600); err != nil {
+		t.Fatalf("write models file: %v", err)
+	}
+	t.Setenv("AGENTOS_MODELS_FILE", path)
+	s := newTestServer(t)
+	if err := s.tenants.Create(types.Tenant{TenantID: "tnt_alias", Policy: &types.TenantPolicy{
+		ModelAliases: map[string]string{"smart": "stub-fast"},
+	}}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+
+	for _, tc := range []struct{ tenant, requested, want string }{
+		{"tnt_alias", "smart", "stub-fast"},
+		{"tnt_alias", "", "local-stub-llm"},
+		{"tnt_other", "smart", "stub-smart"},
+		{"tnt_other", "default", "local-stub-llm"},
+	} {
+		rec := invokeModel(t, s, tc.tenant, types.ModelInvokeRequest{Operation: "chat", ModelID: tc.requested, Input: map[string]any{"text": "hi"}})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %q: expected 200, got %d body=%s", tc.tenant, tc.requested, rec.Code, rec.Body.String())
+		}
+		if got := decodeInvoke(t, rec).ModelID; got != tc.want {
+			t.Fatalf("%s %q: expected %s, got %s", tc.tenant, tc.requested, tc.want, got)
+		}
+	}
+
+	// policy applies to the model an alias resolves to
+	if err := s.tenants.Create(types.Tenant{TenantID: "tnt_nofast", Policy: &types.TenantPolicy{DeniedModels: []string{"stub-fast"}}}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	rec := invokeModel(t, s, "tnt_nofast", types.ModelInvokeRequest{Operation: "chat", ModelID: "fast", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the alias of a denied model to be denied, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package modelpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/httpx"
+)
+
+// handleStream serves /v1/models:stream, an invoke whose output is sent as server-sent
+// events while the model generates it. Quota, policy and budget checks run as for
+// /v1/models:invoke before the stream starts, and failures before the first event are
+// plain JSON errors. Events:
+//
+//	event: delta  data: {"delta": {"text": ...}}   an increment of the output
+//	event: done   data: ModelInvokeResponse        the final output and usage
+//	event: error  data: {"error": {...}}           the provider failed mid-stream
+//
+// Usage is recorded when the stream finishes, including usage consumed before a failure
+// or a client disconnect when the provider reports it. A provider failing before the
+// first delta fails over like an invoke; after it, the stream ends with an error event.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	call, ok := s.admitInvoke(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpx.Error(w, http.StatusInternalServerError, "streaming_unsupported", "streaming unsupported", httpx.CorrelationID(r), false)
+		return
+	}
+
+	started := false
+	send := func(event string, payload any) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	var output, usage map[string]any
+	served, err := s.providers.call(r.Context(), call.route, func(entry providerEntry) error {
+		var err error
+		output, usage, err = entry.impl.Stream(r.Context(), call.requestFor(entry), func(delta map[string]any) error {
+			return send("delta", map[string]any{"delta": delta})
+		})
+		if err != nil {
+			s.usage.Record(call.tenantID, usage)
+			if started {
+				// the client has part of this model's output; a fallback cannot continue it
+				return noFailover{err}
+			}
+		}
+		return err
+	})
+	if err != nil {
+		if !started {
+			s.providerError(w, r, err)
+			return
+		}
+		_ = send("error", map[string]any{
+			"error":          map[string]any{"code": "provider_error", "message": err.Error(), "retryable": true},
+			"correlation_id": httpx.CorrelationID(r),
+		})
+		return
+	}
+	_ = send("done", s.finishInvoke(r, call, served, output, usage))
+}
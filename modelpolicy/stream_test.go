@@ -0,0 +1,186 @@
+package modelpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eyoshidagorgonia/nexixai-agentos-platform/internal/types"
+)
+
+type sseEvent struct {
+	event string
+	data  string
+}
+
+func streamModel(t *testing.T, s *Server, tenantID string, req types.ModelInvokeRequest) (*httptest.ResponseRecorder, []sseEvent) {
+	t.Helper()
+	payload, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/v1/models:stream", bytes.NewReader(payload))
+	httpReq.Header.Set("X-Tenant-Id", tenantID)
+	httpReq.Header.Set("X-Principal-Id", "usr_test")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httpReq)
+
+	var events []sseEvent
+	var cur sseEvent
+	scanner := bufio.NewScanner(bytes.NewReader(rec.Body.Bytes()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			cur.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			cur.data = strings.TrimPrefix(line, "data: ")
+		case line == "" && cur.event != "":
+			events = append(events, cur)
+			cur = sseEvent{}
+		}
+	}
+	return rec, events
+}
+
+// streamResult joins the text deltas of events and decodes the final done event.
+func streamResult(t *testing.T, events []sseEvent) (string, types.ModelInvokeResponse) {
+	t.Helper()
+	var text strings.Builder
+	var done types.ModelInvokeResponse
+	for i, ev := range events {
+		switch ev.event {
+		case "delta":
+			var d struct {
+				Delta map[string]any `json:"delta"`
+			}
+			if err := json.Unmarshal([]byte(ev.data), &d); err != nil {
+				t.Fatalf("decode delta: %v", err)
+			}
+			s, _ := d.Delta["text"].(string)
+			text.WriteString(s)
+		case "done":
+			if i != len(events)-1 {
+				t.Fatalf("expected done to be the last event, got %+v", events)
+			}
+			if err := json.Unmarshal([]byte(ev.data), &done); err != nil {
+				t.Fatalf("decode done: %v", err)
+			}
+		default:
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	}
+	return text.String(), done
+}
+
+func TestStreamEmitsDeltasAndRecordsUsage(t *testing.T) {
+	s := newTestServer(t)
+	rec, events := streamModel(t, s, "tnt_stream", types.ModelInvokeRequest{Operation: "chat", ModelID: "default", Input: map[string]any{"text": "tell me a story"}})
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %q body=%s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	if len(events) < 3 {
+		t.Fatalf("expected several deltas and done, got %+v", events)
+	}
+	text, done := streamResult(t, events)
+	if text != "stub: tell me a story" || done.Output["text"] != text || done.ModelID != "local-stub-llm" {
+		t.Fatalf("expected the deltas to add up to the output, got %q and %+v", text, done)
+	}
+	total, _ := toInt(done.Usage["total_tokens"])
+	if hourly, _ := s.usage.GetUsage("tnt_stream"); total == 0 || hourly != total {
+		t.Fatalf("expected %d tokens metered, got %d", total, hourly)
+	}
+}
+
+func TestStreamChecksBudgetBeforeStreaming(t *testing.T) {
+	s := newTestServer(t)
+	_ = s.tenants.Create(types.Tenant{TenantID: "tnt_stream_budget", Policy: &types.TenantPolicy{
+		TokenBudget: &types.TokenBudget{MaxTokensPerHour: 1},
+	}})
+	req := types.ModelInvokeRequest{Operation: "chat", ModelID: "local-stub-llm", Input: map[string]any{"text": "hello"}}
+	if rec, _ := streamModel(t, s, "tnt_stream_budget", req); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first stream to run, got %d", rec.Code)
+	}
+	rec, events := streamModel(t, s, "tnt_stream_budget", req)
+	if rec.Code != http.StatusForbidden || len(events) != 0 || !strings.Contains(rec.Body.String(), "policy_blocked") {
+		t.Fatalf("expected a 403 JSON error before streaming, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOpenAIProviderStreamsDeltas(t *testing.T) {
+	var got map[string]any
+	newFakeOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":":\"x\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+			`[DONE]`,
+		} {
+			_, _ = w.Write([]byte("data: " + chunk + "\n\n"))
+		}
+	})
+	s := newTestServer(t)
+
+	rec, events := streamModel(t, s, "tnt_openai_stream", types.ModelInvokeRequest{Operation: "chat", ModelID: "gpt-test", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got["stream"] != true {
+		t.Fatalf("expected a streamed upstream request, got %v", got)
+	}
+	text, done := streamResult(t, events)
+	if text != "Hello" || done.Output["text"] != "Hello" || done.Output["finish_reason"] != "tool_calls" {
+		t.Fatalf("unexpected stream result %q %+v", text, done.Output)
+	}
+	calls, _ := done.Output["tool_calls"].([]any)
+	if len(calls) != 1 || calls[0].(map[string]any)["function"].(map[string]any)["arguments"] != `{"q":"x"}` {
+		t.Fatalf("expected the assembled tool call, got %v", done.Output["tool_calls"])
+	}
+	if hourly, _ := s.usage.GetUsage("tnt_openai_stream"); hourly != 15 {
+		t.Fatalf("expected 15 tokens metered, got %d", hourly)
+	}
+}
+
+func TestOpenAIProviderTimeoutOnlyBoundsStreamHeaders(t *testing.T) {
+	newFakeOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{`{"choices":[{"delta":{"content":"slow"}}]}`, `{"choices":[{"delta":{"content":"ly"}}]}`, `[DONE]`} {
+			_, _ = w.Write([]byte("data: " + chunk + "\n\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(60 * time.Millisecond)
+		}
+	})
+	t.Setenv("AGENTOS_OPENAI_TIMEOUT_MS", "100")
+	s := newTestServer(t)
+
+	rec, events := streamModel(t, s, "tnt_slow_stream", types.ModelInvokeRequest{Operation: "chat", ModelID: "gpt-test", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if text, done := streamResult(t, events); text != "slowly" || done.Output["text"] != "slowly" {
+		t.Fatalf("expected the stream to outlive the timeout, got %q %+v", text, done.Output)
+	}
+}
+
+func TestOpenAIProviderStreamClosedMidStreamFails(t *testing.T) {
+	newFakeOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: " + `{"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n"))
+		// the connection closes without [DONE]
+	})
+	s := newTestServer(t)
+
+	rec, events := streamModel(t, s, "tnt_truncated", types.ModelInvokeRequest{Operation: "chat", ModelID: "gpt-test", Input: map[string]any{"text": "hi"}})
+	if rec.Code != http.StatusOK || len(events) != 2 {
+		t.Fatalf("expected a delta then an error event, got %d %+v", rec.Code, events)
+	}
+	if events[0].event != "delta" || events[1].event != "error" || !strings.Contains(events[1].data, "[DONE]") {
+		t.Fatalf("expected the truncated stream reported as an error, got %+v", events)
+	}
+}